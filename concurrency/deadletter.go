@@ -0,0 +1,188 @@
+package concurrency
+
+import "runtime/debug"
+
+// DeadLetter describes the input value whose processing has panicked. Pool also reports
+// the values from its input channel that it drops without processing when it's shut down
+// or stopped, in this case Panic holds the reason, ErrPoolClosed or ErrPoolStopped,
+// and Stack is nil.
+type DeadLetter struct {
+	Input any
+	Panic any
+	Stack []byte
+}
+
+// DeadLetterHandler handles the values whose processing has panicked instead of crashing the process.
+// It's called on the goroutine that has recovered the panic, so a slow handler holds back the processing.
+type DeadLetterHandler func(dl DeadLetter)
+
+// DeadLetterChan returns DeadLetterHandler that sends the dead letters to the channel.
+// The channel should be drained, otherwise the processing blocks.
+func DeadLetterChan(ch chan<- DeadLetter) DeadLetterHandler {
+	return func(dl DeadLetter) {
+		ch <- dl
+	}
+}
+
+// safeCall calls fn for the value. If the handler is set, it recovers the panic,
+// passes the dead letter to the handler and reports false.
+func safeCall(fn WorkerFunc, val any, h DeadLetterHandler) (res any, ok bool) {
+	if h == nil {
+		return fn(val), true
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			h(DeadLetter{Input: val, Panic: r, Stack: debug.Stack()})
+			res, ok = nil, false
+		}
+	}()
+
+	return fn(val), true
+}
+
+// RecoverJob wraps Job to recover its panics, so a value making the job panic doesn't crash
+// the process. The panic is passed to the handler along with the value the job has received
+// most recently, then the job is restarted to continue with the next values.
+//
+// RecoverJob sees only the job's channels, not which value it's processing, so the reported
+// input is a guess that holds for the jobs handling one value at a time. A job that buffers
+// or batches the values may panic on an earlier one, and the values it holds at the moment
+// of the panic are lost without being reported. Such a job should recover the panics itself,
+// per value, or be built by Stage with DeadLetters option.
+//
+// The recovery is opt-in: the panics of the jobs that aren't wrapped, as well as the ones
+// of the functions given to Stage or Pool without DeadLetters or WithDeadLetters option,
+// still crash the process.
+//
+// If the job panics again without receiving a new value, the panic isn't caused by a value,
+// so restarting the job would only repeat it. In that case the dead letter has no input,
+// the job isn't restarted, and the rest of the input is drained and dropped, so the stage
+// finishes without blocking the previous one. The nil handler means no recovery,
+// just like with DeadLetters.
+func RecoverJob(job Job, h DeadLetterHandler) Job {
+	if h == nil {
+		return job
+	}
+
+	return func(in <-chan any, out chan<- any) {
+		p := &jobProxy{
+			out:    make(chan any),
+			halt:   make(chan struct{}),
+			lastCh: make(chan delivery),
+			done:   make(chan struct{}),
+		}
+
+		defer close(p.done)
+
+		go p.forward(in)
+
+		// delivered is the number of the values delivered to the job by the time of the last panic.
+		delivered := 0
+		restart := true
+
+		for restart && !runRecovered(job, p.out, out, func(r any, stack []byte) {
+			last, n := p.last()
+			if n == delivered {
+				last, restart = nil, false
+			}
+
+			delivered = n
+			h(DeadLetter{Input: last, Panic: r, Stack: stack})
+		}) {
+		}
+	}
+}
+
+// jobProxy forwards the input to the job and remembers the value delivered most recently
+// along with the number of the delivered values. The job's goroutine asks for them through
+// the halt channel, so the next value can't be delivered at the same time it's being asked.
+// Once the job is done, the proxy drains the rest of the input.
+type jobProxy struct {
+	out    chan any
+	halt   chan struct{}
+	lastCh chan delivery
+	done   chan struct{}
+}
+
+// delivery is the value delivered to the job most recently and the number of the delivered values.
+type delivery struct {
+	val any
+	n   int
+}
+
+func (p *jobProxy) forward(in <-chan any) {
+	var last delivery
+
+	drain := func() {
+		for range in {
+		}
+	}
+
+	defer func() {
+		close(p.out)
+
+		// The job may still panic on the last value after the input is closed.
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-p.halt:
+				p.lastCh <- last
+			}
+		}
+	}()
+
+	for {
+		var val any
+
+		select {
+		case <-p.done:
+			drain()
+			return
+		case <-p.halt:
+			p.lastCh <- last
+			continue
+		case v, ok := <-in:
+			if !ok {
+				return
+			}
+
+			val = v
+		}
+
+		for delivered := false; !delivered; {
+			select {
+			case <-p.done:
+				drain()
+				return
+			case <-p.halt:
+				p.lastCh <- last
+			case p.out <- val:
+				last = delivery{val: val, n: last.n + 1}
+				delivered = true
+			}
+		}
+	}
+}
+
+func (p *jobProxy) last() (any, int) {
+	p.halt <- struct{}{}
+	d := <-p.lastCh
+
+	return d.val, d.n
+}
+
+// runRecovered runs the job and reports whether it has returned without panic.
+func runRecovered(job Job, in <-chan any, out chan<- any, onPanic func(r any, stack []byte)) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			onPanic(r, debug.Stack())
+			ok = false
+		}
+	}()
+
+	job(in, out)
+
+	return true
+}
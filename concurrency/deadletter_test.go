@@ -0,0 +1,215 @@
+package concurrency
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func panicOnThree(in any) any {
+	if in == 3 {
+		panic("three is unlucky")
+	}
+
+	return in
+}
+
+func checkDeadLetters(t *testing.T, dls []DeadLetter) {
+	t.Helper()
+
+	if len(dls) != 1 {
+		t.Fatalf("wrong number of dead letters: got %d, want 1", len(dls))
+	}
+
+	dl := dls[0]
+	if dl.Input != 3 || dl.Panic != "three is unlucky" {
+		t.Errorf("wrong dead letter: got input %v, panic %v", dl.Input, dl.Panic)
+	}
+
+	if !strings.Contains(string(dl.Stack), "panicOnThree") {
+		t.Errorf("stack doesn't point to the panicking function:\n%s", dl.Stack)
+	}
+}
+
+func TestStageDeadLetters(t *testing.T) {
+	vals := []int{1, 2, 3, 4, 5}
+
+	testCases := []struct {
+		name string
+		opts []StageOption
+	}{
+		{name: "single worker"},
+		{name: "parallel", opts: []StageOption{Workers(3)}},
+		{name: "parallel ordered", opts: []StageOption{Workers(3), Ordered()}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutils.DetectGoroutineLeeks(t, func() {
+				dlCh := make(chan DeadLetter, 1)
+				opts := append(tc.opts, DeadLetters(DeadLetterChan(dlCh)))
+
+				in, out := Pipeline(Stage(panicOnThree, opts...))
+				got := runPipeline(in, out, vals)
+				close(dlCh)
+
+				sort.Ints(got)
+
+				if want := []int{1, 2, 4, 5}; !reflect.DeepEqual(got, want) {
+					t.Errorf("wrong pipeline result: got %v, want %v", got, want)
+				}
+
+				checkDeadLetters(t, collect(dlCh))
+			})
+		})
+	}
+}
+
+func TestRecoverJob(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		var dls []DeadLetter
+
+		job := func(in <-chan any, out chan<- any) {
+			for val := range in {
+				out <- panicOnThree(val)
+			}
+		}
+
+		in, out := Pipeline(RecoverJob(job, func(dl DeadLetter) {
+			dls = append(dls, dl)
+		}))
+		got := runPipeline(in, out, []int{1, 2, 3, 4, 5})
+
+		if want := []int{1, 2, 4, 5}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong pipeline result: got %v, want %v", got, want)
+		}
+
+		checkDeadLetters(t, dls)
+	})
+}
+
+func TestRecoverJobPanicOnLastValue(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		var dls []DeadLetter
+
+		job := func(in <-chan any, out chan<- any) {
+			for val := range in {
+				out <- panicOnThree(val)
+			}
+		}
+
+		in, out := Pipeline(RecoverJob(job, func(dl DeadLetter) {
+			dls = append(dls, dl)
+		}))
+		got := runPipeline(in, out, []int{1, 2, 3})
+
+		if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong pipeline result: got %v, want %v", got, want)
+		}
+
+		checkDeadLetters(t, dls)
+	})
+}
+
+func TestRecoverJobPanicWithoutInput(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		var dls []DeadLetter
+
+		job := func(in <-chan any, out chan<- any) {
+			var m map[string]int
+			m["boom"]++
+		}
+
+		in, out := Pipeline(RecoverJob(job, func(dl DeadLetter) {
+			dls = append(dls, dl)
+		}))
+
+		// The job isn't restarted, and the values are dropped without blocking the sender.
+		if got := runPipeline(in, out, []int{1, 2, 3}); len(got) != 0 {
+			t.Errorf("wrong pipeline result: got %v, want none", got)
+		}
+
+		if len(dls) != 1 {
+			t.Fatalf("wrong number of dead letters: got %d, want 1", len(dls))
+		}
+
+		if dls[0].Input != nil {
+			t.Errorf("wrong dead letter input: got %v, want nil", dls[0].Input)
+		}
+	})
+}
+
+func TestRecoverJobRepeatedPanic(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		var dls []DeadLetter
+
+		// After the first panic, the job panics straightaway on every restart.
+		var broken bool
+
+		job := func(in <-chan any, out chan<- any) {
+			if broken {
+				panic("broken")
+			}
+
+			for val := range in {
+				if val == 2 {
+					broken = true
+				}
+
+				out <- panicOnThree(val)
+			}
+		}
+
+		in, out := Pipeline(RecoverJob(job, func(dl DeadLetter) {
+			dls = append(dls, dl)
+		}))
+		got := runPipeline(in, out, []int{1, 2, 3, 4, 5})
+
+		if want := []int{1, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong pipeline result: got %v, want %v", got, want)
+		}
+
+		if len(dls) != 2 {
+			t.Fatalf("wrong number of dead letters: got %d, want 2", len(dls))
+		}
+
+		checkDeadLetters(t, dls[:1])
+
+		if dls[1].Input != nil || dls[1].Panic != "broken" {
+			t.Errorf("wrong second dead letter: got input %v and panic %v", dls[1].Input, dls[1].Panic)
+		}
+	})
+}
+
+func TestRecoverJobNilHandler(t *testing.T) {
+	in, out := Pipeline(RecoverJob(func(in <-chan any, out chan<- any) {
+		for val := range in {
+			out <- val
+		}
+	}, nil))
+
+	if got, want := runPipeline(in, out, []int{1, 2, 3}), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong pipeline result: got %v, want %v", got, want)
+	}
+}
+
+func TestWorkerPoolDeadLetters(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		dlCh := make(chan DeadLetter, 1)
+
+		in, out := WorkerPool(3, panicOnThree, WithDeadLetters(DeadLetterChan(dlCh)))
+		got := runPipeline(in, out, []int{1, 2, 3, 4, 5})
+		close(dlCh)
+
+		sort.Ints(got)
+
+		if want := []int{1, 2, 4, 5}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong worker pool result: got %v, want %v", got, want)
+		}
+
+		checkDeadLetters(t, collect(dlCh))
+	})
+}
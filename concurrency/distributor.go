@@ -0,0 +1,91 @@
+package concurrency
+
+import "hash/fnv"
+
+// Distributor decides which destination channels of FanOutWith receive the value.
+// Its methods are called from a single goroutine, so an implementation doesn't
+// have to be safe for concurrent use unless it's shared among several FanOutWith calls.
+type Distributor[T any] interface {
+	// Distribute returns the indexes of the destinations the value should be sent to.
+	// The depths hold the number of values queued in each destination channel,
+	// its length is the number of destinations, which is never zero.
+	Distribute(val T, depths []int) []int
+}
+
+// DistributorFunc is an adapter to allow the use of ordinary functions as Distributor.
+type DistributorFunc[T any] func(val T, depths []int) []int
+
+// Distribute calls fn(val, depths).
+func (fn DistributorFunc[T]) Distribute(val T, depths []int) []int {
+	return fn(val, depths)
+}
+
+// RoundRobin returns Distributor that sends values to the destinations in turn.
+func RoundRobin[T any]() Distributor[T] {
+	var next int
+
+	return DistributorFunc[T](func(_ T, depths []int) []int {
+		if len(depths) == 0 {
+			return nil
+		}
+
+		idx := next % len(depths)
+		next = idx + 1
+
+		return []int{idx}
+	})
+}
+
+// LeastLoaded returns Distributor that sends every value to the destination
+// with the least number of queued values. The ties are broken in the round-robin order,
+// so the destinations without queued values are loaded evenly.
+func LeastLoaded[T any]() Distributor[T] {
+	var start int
+
+	return DistributorFunc[T](func(_ T, depths []int) []int {
+		n := len(depths)
+		if n == 0 {
+			return nil
+		}
+
+		best := start % n
+
+		for i := 1; i < n; i++ {
+			idx := (start + i) % n
+			if depths[idx] < depths[best] {
+				best = idx
+			}
+		}
+
+		start = best + 1
+
+		return []int{best}
+	})
+}
+
+// KeyAffinity returns Distributor that hashes the key of the value to choose the destination,
+// so the values with the same key always reach the same destination.
+func KeyAffinity[T any](key func(val T) string) Distributor[T] {
+	return DistributorFunc[T](func(val T, depths []int) []int {
+		if len(depths) == 0 {
+			return nil
+		}
+
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key(val)))
+
+		return []int{int(h.Sum32() % uint32(len(depths)))}
+	})
+}
+
+// Broadcast returns Distributor that sends every value to all the destinations.
+func Broadcast[T any]() Distributor[T] {
+	return DistributorFunc[T](func(_ T, depths []int) []int {
+		idxs := make([]int, len(depths))
+		for i := range idxs {
+			idxs[i] = i
+		}
+
+		return idxs
+	})
+}
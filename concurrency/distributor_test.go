@@ -0,0 +1,90 @@
+package concurrency
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRoundRobin(t *testing.T) {
+	d := RoundRobin[int]()
+	depths := make([]int, 3)
+
+	var got []int
+	for i := 0; i < 7; i++ {
+		got = append(got, d.Distribute(i, depths)...)
+	}
+
+	want := []int{0, 1, 2, 0, 1, 2, 0}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong round-robin distribution: got %v, want %v", got, want)
+	}
+}
+
+func TestLeastLoaded(t *testing.T) {
+	d := LeastLoaded[int]()
+
+	testCases := []struct {
+		depths []int
+		want   int
+	}{
+		{depths: []int{3, 1, 2}, want: 1},
+		{depths: []int{0, 5, 0}, want: 2},
+		{depths: []int{0, 5, 0}, want: 0},
+		{depths: []int{0, 0, 0}, want: 1},
+		{depths: []int{4, 4, 3}, want: 2},
+	}
+
+	for _, tc := range testCases {
+		got := d.Distribute(0, tc.depths)
+
+		if !reflect.DeepEqual(got, []int{tc.want}) {
+			t.Errorf("wrong least-loaded distribution for depths %v: got %v, want [%d]", tc.depths, got, tc.want)
+		}
+	}
+}
+
+func TestKeyAffinity(t *testing.T) {
+	d := KeyAffinity(func(val string) string { return val })
+	depths := make([]int, 4)
+	seen := make(map[string]int)
+
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"alpha", "beta", "gamma", "delta", "epsilon"} {
+			idxs := d.Distribute(key, depths)
+			if len(idxs) != 1 {
+				t.Fatalf("expected the single destination, got %v", idxs)
+			}
+
+			if idx, ok := seen[key]; ok && idx != idxs[0] {
+				t.Errorf("key %s moved from destination %d to %d", key, idx, idxs[0])
+			}
+
+			seen[key] = idxs[0]
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	got := Broadcast[int]().Distribute(0, make([]int, 3))
+	want := []int{0, 1, 2}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong broadcast distribution: got %v, want %v", got, want)
+	}
+}
+
+func TestDistributorsWithoutDestinations(t *testing.T) {
+	distributors := map[string]Distributor[int]{
+		"round robin":  RoundRobin[int](),
+		"least loaded": LeastLoaded[int](),
+		"key affinity": KeyAffinity(func(val int) string { return "key" }),
+		"broadcast":    Broadcast[int](),
+	}
+
+	for name, d := range distributors {
+		if idxs := d.Distribute(0, nil); len(idxs) != 0 {
+			t.Errorf("%s distribution without destinations: got %v, want none", name, idxs)
+		}
+	}
+}
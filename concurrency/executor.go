@@ -0,0 +1,200 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrExecutorRejected is returned when the task is rejected by RejectAbort policy.
+	ErrExecutorRejected = errors.New("executor queue is full")
+	// ErrExecutorShutdown is returned when the task is submitted to the shut down Executor.
+	ErrExecutorShutdown = errors.New("executor is shut down")
+)
+
+// RejectionPolicy defines what Executor does with the task when its queue is full.
+type RejectionPolicy int
+
+const (
+	// RejectBlock waits until the queue has room for the task or the context is done.
+	RejectBlock RejectionPolicy = iota
+	// RejectAbort fails the task's Future with ErrExecutorRejected.
+	RejectAbort
+	// RejectCallerRuns performs the task in the submitting goroutine, which
+	// naturally slows the submitter down.
+	RejectCallerRuns
+)
+
+type executorConfig struct {
+	policy RejectionPolicy
+}
+
+// ExecutorOption configures Executor.
+type ExecutorOption func(cfg *executorConfig)
+
+// WithRejectionPolicy sets the policy applied when the queue is full. Default is RejectBlock.
+func WithRejectionPolicy(p RejectionPolicy) ExecutorOption {
+	return func(cfg *executorConfig) {
+		cfg.policy = p
+	}
+}
+
+// Executor performs the submitted tasks on a fixed number of worker goroutines.
+// Every RunAsync call spawns its own goroutines, so a burst of calls may spawn the unbounded
+// number of them. Executor bounds both the number of goroutines and the number of tasks
+// waiting for a free worker, the tasks that don't fit into the queue are handled according
+// to RejectionPolicy. RunAsync and Async use Executor if it's passed with WithExecutor option.
+//
+// The Future of the submitted task behaves like the one of Async: it fails with the context error
+// as soon as the context is done, even if the task is still waiting in the queue, so the caller
+// doesn't wait for the queue to move. Such a task isn't performed when a worker takes it.
+// The Future watches the context in a separate goroutine only if the context can be done, and
+// only until the task completes, so there are no more such goroutines than the tasks in progress.
+type Executor struct {
+	tasks  chan func()
+	policy RejectionPolicy
+	wg     sync.WaitGroup
+
+	// mu guards closing the tasks channel from the concurrent submits.
+	mu       sync.RWMutex
+	closed   bool
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// NewExecutor constructs Executor. It takes the number of workers and the size of the queue
+// of the tasks waiting for a free worker. Both are at least 1, the lesser values are raised to 1.
+func NewExecutor(workers, queueSize int, opts ...ExecutorOption) *Executor {
+	var cfg executorConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	e := &Executor{
+		tasks:  make(chan func(), queueSize),
+		policy: cfg.policy,
+		quit:   make(chan struct{}),
+	}
+
+	e.wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer e.wg.Done()
+
+			for task := range e.tasks {
+				task()
+			}
+		}()
+	}
+
+	return e
+}
+
+// Submit is the Executor's counterpart of RunAsync. It submits SlowFunc to the Executor and returns Future.
+func (e *Executor) Submit(ctx context.Context, fn SlowFunc) Future[string] {
+	return Submit(ctx, e, func(context.Context) (string, error) {
+		return fn()
+	})
+}
+
+// Submit is the generic version of Executor.Submit. It submits AsyncFunc to the Executor and returns Future.
+func Submit[T any](ctx context.Context, e *Executor, fn AsyncFunc[T]) Future[T] {
+	p, ctx := newPromise[T](ctx)
+	f := p.future
+
+	var zero T
+
+	task := func() {
+		if err := ctx.Err(); err != nil {
+			f.complete(zero, err)
+			return
+		}
+
+		result, err := fn(ctx)
+		f.complete(result, err)
+	}
+
+	if err := e.execute(ctx, task); err != nil {
+		f.complete(zero, err)
+	}
+
+	return f
+}
+
+// Shutdown stops accepting new tasks and waits until the workers perform the tasks
+// already in the queue. If the context is done first, Shutdown returns the context error,
+// the workers keep performing the remaining tasks in the background.
+func (e *Executor) Shutdown(ctx context.Context) error {
+	e.quitOnce.Do(func() {
+		// Unblocks the submits waiting for room in the queue before taking the lock.
+		close(e.quit)
+
+		e.mu.Lock()
+		e.closed = true
+		close(e.tasks)
+		e.mu.Unlock()
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
+// execute puts the task into the queue applying the rejection policy if it's full.
+func (e *Executor) execute(ctx context.Context, task func()) error {
+	e.mu.RLock()
+
+	if e.closed {
+		e.mu.RUnlock()
+		return ErrExecutorShutdown
+	}
+
+	select {
+	case e.tasks <- task:
+		e.mu.RUnlock()
+		return nil
+	default:
+	}
+
+	switch e.policy {
+	case RejectAbort:
+		e.mu.RUnlock()
+		return ErrExecutorRejected
+	case RejectCallerRuns:
+		e.mu.RUnlock()
+		task()
+
+		return nil
+	}
+
+	defer e.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.quit:
+		return ErrExecutorShutdown
+	case e.tasks <- task:
+		return nil
+	}
+}
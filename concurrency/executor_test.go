@@ -0,0 +1,248 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+// blockingTask returns the function that blocks until the release channel is closed.
+func blockingTask(release <-chan struct{}) AsyncFunc[int] {
+	return func(ctx context.Context) (int, error) {
+		<-release
+		return 1, nil
+	}
+}
+
+// occupyWorker submits the blocking task and waits until a worker starts performing it.
+func occupyWorker(e *Executor, release <-chan struct{}) {
+	started := make(chan struct{})
+
+	Submit(context.Background(), e, func(ctx context.Context) (int, error) {
+		close(started)
+		return blockingTask(release)(ctx)
+	})
+
+	<-started
+}
+
+func TestExecutorBounded(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		const workers = 3
+
+		var running, maxRunning int64
+		e := NewExecutor(workers, 100)
+		futures := make([]Future[int], 30)
+
+		for i := range futures {
+			futures[i] = Submit(context.Background(), e, func(ctx context.Context) (int, error) {
+				n := atomic.AddInt64(&running, 1)
+				for {
+					max := atomic.LoadInt64(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+						break
+					}
+				}
+
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt64(&running, -1)
+
+				return 1, nil
+			})
+		}
+
+		res, err := All(context.Background(), futures...).Result()
+		if err != nil || len(res) != len(futures) {
+			t.Errorf("wrong result: got (%v, %v)", res, err)
+		}
+
+		if maxRunning > workers {
+			t.Errorf("too many concurrent tasks: got %d, want at most %d", maxRunning, workers)
+		}
+
+		if err = e.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestExecutorInvalidSize(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		e := NewExecutor(0, -1)
+
+		if n := cap(e.tasks); n != 1 {
+			t.Errorf("wrong queue size: got %d, want 1", n)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		res, err := Submit(ctx, e, func(ctx context.Context) (int, error) { return 1, nil }).Result()
+		if err != nil || res != 1 {
+			t.Errorf("wrong result: got (%v, %v), want (1, <nil>)", res, err)
+		}
+
+		if err = e.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestExecutorRejectionPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("abort", func(t *testing.T) {
+		release := make(chan struct{})
+		e := NewExecutor(1, 1, WithRejectionPolicy(RejectAbort))
+
+		occupyWorker(e, release)
+		Submit(ctx, e, blockingTask(release))
+
+		if _, err := Submit(ctx, e, blockingTask(release)).Result(); !errors.Is(err, ErrExecutorRejected) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		close(release)
+		_ = e.Shutdown(ctx)
+	})
+
+	t.Run("caller runs", func(t *testing.T) {
+		release := make(chan struct{})
+		e := NewExecutor(1, 1, WithRejectionPolicy(RejectCallerRuns))
+
+		occupyWorker(e, release)
+		Submit(ctx, e, blockingTask(release))
+
+		f := Submit(ctx, e, func(ctx context.Context) (int, error) { return 42, nil })
+
+		select {
+		case <-f.Done():
+		default:
+			t.Error("task wasn't performed by the caller")
+		}
+
+		close(release)
+		_ = e.Shutdown(ctx)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		release := make(chan struct{})
+		e := NewExecutor(1, 1)
+
+		occupyWorker(e, release)
+		Submit(ctx, e, blockingTask(release))
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		if _, err := Submit(timeoutCtx, e, blockingTask(release)).Result(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		close(release)
+		_ = e.Shutdown(ctx)
+	})
+}
+
+func TestExecutorShutdown(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		ctx := context.Background()
+		release := make(chan struct{})
+		e := NewExecutor(1, 5)
+
+		futures := make([]Future[int], 5)
+		for i := range futures {
+			futures[i] = Submit(ctx, e, blockingTask(release))
+		}
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		if err := e.Shutdown(timeoutCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		if _, err := e.Submit(ctx, slowFunction).Result(); !errors.Is(err, ErrExecutorShutdown) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		close(release)
+
+		if err := e.Shutdown(ctx); err != nil {
+			t.Error(err)
+		}
+
+		// The queued tasks are drained, not dropped.
+		for _, f := range futures {
+			if _, err := f.Result(); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+}
+
+func TestExecutorQueuedTaskCanceled(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		release := make(chan struct{})
+		e := NewExecutor(1, 5)
+		occupyWorker(e, release)
+
+		var performed int64
+
+		ctx, cancel := context.WithCancel(context.Background())
+		f := Submit(ctx, e, func(context.Context) (int, error) {
+			atomic.AddInt64(&performed, 1)
+			return 1, nil
+		})
+
+		cancel()
+
+		// The Future fails without waiting for the worker to be released.
+		resCtx, resCancel := context.WithTimeout(context.Background(), time.Second)
+		defer resCancel()
+
+		if _, err := f.ResultContext(resCtx); !errors.Is(err, context.Canceled) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		close(release)
+
+		if err := e.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+
+		if n := atomic.LoadInt64(&performed); n != 0 {
+			t.Errorf("canceled task is performed %d times", n)
+		}
+	})
+}
+
+func TestRunAsyncWithExecutor(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		release := make(chan struct{})
+		e := NewExecutor(1, 1, WithRejectionPolicy(RejectAbort))
+		occupyWorker(e, release)
+
+		quick := func() (string, error) { return "done", nil }
+		queued := RunAsync(context.Background(), quick, WithExecutor(e))
+
+		// The only worker is busy and the queue is full, so the executor rejects the function.
+		if _, err := RunAsync(context.Background(), quick, WithExecutor(e)).Result(); !errors.Is(err, ErrExecutorRejected) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		close(release)
+
+		if _, err := queued.Result(); err != nil {
+			t.Error(err)
+		}
+
+		if err := e.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+}
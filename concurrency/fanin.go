@@ -1,6 +1,9 @@
 package concurrency
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // FanIn pattern combines multiply inputs into one single output channel.
 // Services that have some number of workers that all generate output may find it useful
@@ -9,14 +12,22 @@ import "sync"
 // FanIn is implemented as a function that receives N source channels. For each
 // input channel FanIn starts a separate goroutine to read values from its assigned channel and
 // forward all the values to a single destination channel shared by all the goroutines.
+//
+// FanIn is kept for int channels, see FanInOf for the generic version.
 func FanIn(sources ...<-chan int) <-chan int {
-	dest := make(chan int)
+	return FanInOf(sources...)
+}
+
+// FanInOf is the generic version of FanIn that combines the source channels
+// carrying values of any type T.
+func FanInOf[T any](sources ...<-chan T) <-chan T {
+	dest := make(chan T)
 
 	var wg sync.WaitGroup
 	wg.Add(len(sources))
 
 	for _, src := range sources {
-		go func(ch <-chan int) {
+		go func(ch <-chan T) {
 			defer wg.Done()
 
 			for val := range ch {
@@ -32,3 +43,43 @@ func FanIn(sources ...<-chan int) <-chan int {
 
 	return dest
 }
+
+// FanInContext is the same as FanInOf, but it also stops forwarding when the context
+// is done. In this case every forwarding goroutine exits, even if it's blocked on sending
+// the value nobody reads anymore, and the destination channel is closed.
+func FanInContext[T any](ctx context.Context, sources ...<-chan T) <-chan T {
+	dest := make(chan T)
+
+	var wg sync.WaitGroup
+	wg.Add(len(sources))
+
+	for _, src := range sources {
+		go func(ch <-chan T) {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case val, ok := <-ch:
+					if !ok {
+						return
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case dest <- val:
+					}
+				}
+			}
+		}(src)
+	}
+
+	go func() {
+		wg.Wait()
+		close(dest)
+	}()
+
+	return dest
+}
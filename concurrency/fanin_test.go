@@ -1,10 +1,20 @@
 package concurrency
 
 import (
+	"context"
+	"fmt"
+	"reflect"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
 )
 
+// leekGracePeriod is how long the tests wait for the goroutines that are already finishing
+// (e.g. right after the context is canceled) to exit before reporting them as leeked.
+const leekGracePeriod = 100 * time.Millisecond
+
 func TestFanIn(t *testing.T) {
 	var expectedTotalSum int64
 	sources := make([]<-chan int, 3)
@@ -37,3 +47,58 @@ func TestFanIn(t *testing.T) {
 		t.Errorf("wrong FanIn result: got %d, want %d", totalSum, expectedTotalSum)
 	}
 }
+
+func TestFanInOf(t *testing.T) {
+	sources := make([]<-chan string, 3)
+	want := make(map[string]bool)
+
+	for i := range sources {
+		src := make(chan string)
+		sources[i] = src
+
+		words := []string{fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)}
+		for _, w := range words {
+			want[w] = true
+		}
+
+		go func() {
+			for _, w := range words {
+				src <- w
+			}
+
+			close(src)
+		}()
+	}
+
+	got := make(map[string]bool)
+	for val := range FanInOf(sources...) {
+		got[val] = true
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong FanInOf result: got %v, want %v", got, want)
+	}
+}
+
+func TestFanInContext(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		sources := make([]<-chan int, 3)
+
+		for i := range sources {
+			sources[i] = Generator(ctx)
+		}
+
+		dest := FanInContext(ctx, sources...)
+
+		for i := 0; i < 5; i++ {
+			<-dest
+		}
+
+		// Nobody reads dest anymore, so the forwarding goroutines are blocked on sending.
+		cancel()
+
+		for range dest {
+		}
+	})
+}
@@ -1,5 +1,11 @@
 package concurrency
 
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
 // FanOut pattern distributes messages from single input channel to N multiply output channels.
 // It's a useful pattern for parallelizing CPU and I/O utilization. Rather than coupling
 // the input and computation processes in a single serial process, you might prefer to
@@ -9,11 +15,18 @@ package concurrency
 // representing the desired number of destination channels. FanOut creates the N destination
 // channels and separate goroutines for each destination channels that compete to read the next
 // value from source channel and forward to their respective destination channel.
+//
+// FanOut is kept for int channels, see FanOutOf for the generic version.
 func FanOut(src <-chan int, n int) []<-chan int {
-	dests := make([]<-chan int, n)
+	return FanOutOf(src, n)
+}
+
+// FanOutOf is the generic version of FanOut that distributes values of any type T.
+func FanOutOf[T any](src <-chan T, n int) []<-chan T {
+	dests := make([]<-chan T, n)
 
 	for i := range dests {
-		dest := make(chan int)
+		dest := make(chan T)
 		dests[i] = dest
 
 		go func() {
@@ -27,3 +40,244 @@ func FanOut(src <-chan int, n int) []<-chan int {
 
 	return dests
 }
+
+// FanOutContext is the same as FanOutOf, but it also stops distributing when the context
+// is done. In this case every goroutine exits, even if it's blocked on sending the value
+// nobody reads anymore, and all the destination channels are closed.
+func FanOutContext[T any](ctx context.Context, src <-chan T, n int) []<-chan T {
+	dests := make([]<-chan T, n)
+
+	for i := range dests {
+		dest := make(chan T)
+		dests[i] = dest
+
+		go func() {
+			defer close(dest)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case val, ok := <-src:
+					if !ok {
+						return
+					}
+
+					select {
+					case <-ctx.Done():
+						return
+					case dest <- val:
+					}
+				}
+			}
+		}()
+	}
+
+	return dests
+}
+
+// OverflowPolicy defines what FanOutWith does with a value when the destination
+// channel chosen for it is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the destination has room for the value.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the value.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest value buffered in the destination to make room
+	// for the new one. An unbuffered destination has nothing to discard, so the new value is dropped.
+	OverflowDropOldest
+	// OverflowReroute sends the value to the next destination that has room for it.
+	// If all the destinations are full, it waits like OverflowBlock.
+	OverflowReroute
+)
+
+// FanOutStats counts the values that FanOutWith dropped or rerouted according to its OverflowPolicy.
+// It's safe to read the counters while the values are being distributed.
+type FanOutStats struct {
+	dropped  atomic.Int64
+	rerouted atomic.Int64
+}
+
+// Dropped returns the number of values dropped by OverflowDropNewest or OverflowDropOldest.
+func (s *FanOutStats) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Rerouted returns the number of values sent to another destination by OverflowReroute.
+func (s *FanOutStats) Rerouted() int64 {
+	return s.rerouted.Load()
+}
+
+type fanOutConfig struct {
+	bufSize int
+	policy  OverflowPolicy
+	stats   *FanOutStats
+}
+
+// FanOutOption configures FanOutWith.
+type FanOutOption func(cfg *fanOutConfig)
+
+// WithBufferSize sets the buffer size of every destination channel. By default, they are unbuffered.
+// Negative n is treated as 0.
+func WithBufferSize(n int) FanOutOption {
+	return func(cfg *fanOutConfig) {
+		if n < 0 {
+			n = 0
+		}
+
+		cfg.bufSize = n
+	}
+}
+
+// WithOverflowPolicy sets the policy applied when the destination is full. Default is OverflowBlock.
+func WithOverflowPolicy(p OverflowPolicy) FanOutOption {
+	return func(cfg *fanOutConfig) {
+		cfg.policy = p
+	}
+}
+
+// WithFanOutStats sets the counters to report the dropped and rerouted values to.
+func WithFanOutStats(s *FanOutStats) FanOutOption {
+	return func(cfg *fanOutConfig) {
+		cfg.stats = s
+	}
+}
+
+// FanOutWith is a FanOut variant where the destination of every value is explicitly
+// chosen by the Distributor instead of goroutines competing for the next value.
+// A single goroutine reads the source channel and sends each value to the destinations
+// returned by the Distributor. If the distributor is nil, FanOutWith falls back to
+// the competing consumers behavior of FanOutContext.
+//
+// By default, the destinations are unbuffered and the value is sent to the destination
+// only when its consumer is ready to receive it, so a slow consumer holds back the
+// distribution. The options allow to buffer the destinations and choose the OverflowPolicy
+// to apply when the buffer is full.
+//
+// All the destination channels are closed when the source is drained or the context is done.
+// If n isn't positive, FanOutWith returns no destinations and doesn't read the source.
+func FanOutWith[T any](ctx context.Context, src <-chan T, n int, d Distributor[T], opts ...FanOutOption) []<-chan T {
+	if n <= 0 {
+		return []<-chan T{}
+	}
+
+	cfg := fanOutConfig{stats: &FanOutStats{}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	chans := make([]chan T, n)
+	dests := make([]<-chan T, n)
+
+	for i := range chans {
+		chans[i] = make(chan T, cfg.bufSize)
+		dests[i] = chans[i]
+	}
+
+	var wg sync.WaitGroup
+
+	// forward sends the source values to the destinations chosen by pick.
+	forward := func(pick func(val T, depths []int) []int) {
+		defer wg.Done()
+
+		depths := make([]int, n)
+
+		for {
+			val, ok, done := recvContext(ctx, src)
+			if !ok || done {
+				return
+			}
+
+			for i, ch := range chans {
+				depths[i] = len(ch)
+			}
+
+			for _, idx := range pick(val, depths) {
+				if !deliver(ctx, chans, idx, val, &cfg) {
+					return
+				}
+			}
+		}
+	}
+
+	if d != nil {
+		wg.Add(1)
+		go forward(d.Distribute)
+	} else {
+		wg.Add(n)
+
+		for i := range chans {
+			idx := []int{i}
+			go forward(func(T, []int) []int { return idx })
+		}
+	}
+
+	// The destinations are closed together, since a rerouted value
+	// may be sent to any of them.
+	go func() {
+		wg.Wait()
+
+		for _, ch := range chans {
+			close(ch)
+		}
+	}()
+
+	return dests
+}
+
+// deliver sends the value to the idx-th destination applying the overflow policy if it's full.
+// It returns false if the context is done.
+func deliver[T any](ctx context.Context, chans []chan T, idx int, val T, cfg *fanOutConfig) bool {
+	select {
+	case chans[idx] <- val:
+		return true
+	default:
+	}
+
+	switch cfg.policy {
+	case OverflowDropNewest:
+		cfg.stats.dropped.Add(1)
+		return true
+	case OverflowDropOldest:
+		if cap(chans[idx]) == 0 {
+			// There is nothing buffered to discard, so the new value goes.
+			cfg.stats.dropped.Add(1)
+			return true
+		}
+
+		// Only the caller sends to this destination with this policy,
+		// so it has room as soon as the oldest value is gone.
+		for {
+			select {
+			case <-chans[idx]:
+				cfg.stats.dropped.Add(1)
+			default:
+				// The consumer has just taken the oldest value itself.
+			}
+
+			select {
+			case chans[idx] <- val:
+				return true
+			default:
+			}
+		}
+	case OverflowReroute:
+		for i := 1; i < len(chans); i++ {
+			select {
+			case chans[(idx+i)%len(chans)] <- val:
+				cfg.stats.rerouted.Add(1)
+				return true
+			default:
+			}
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case chans[idx] <- val:
+		return true
+	}
+}
@@ -1,9 +1,16 @@
 package concurrency
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
 )
 
 func TestFanOut(t *testing.T) {
@@ -49,3 +56,302 @@ func TestFanOut(t *testing.T) {
 		t.Errorf("wrong fan out result: got %d, want %d", totalSum, expectedTotalSum)
 	}
 }
+
+func TestFanOutOf(t *testing.T) {
+	const n = 3
+	words := []string{"alpha", "beta", "gamma", "delta", "epsilon"}
+
+	src := make(chan string)
+
+	go func() {
+		for _, w := range words {
+			src <- w
+		}
+
+		close(src)
+	}()
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		got []string
+	)
+
+	dests := FanOutOf(src, n)
+	wg.Add(n)
+
+	for _, dest := range dests {
+		go func(dest <-chan string) {
+			defer wg.Done()
+
+			for val := range dest {
+				mu.Lock()
+				got = append(got, val)
+				mu.Unlock()
+			}
+		}(dest)
+	}
+
+	wg.Wait()
+	sort.Strings(got)
+	sort.Strings(words)
+
+	if !reflect.DeepEqual(got, words) {
+		t.Errorf("wrong fan out result: got %v, want %v", got, words)
+	}
+}
+
+func TestFanOutContext(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		const n = 3
+		ctx, cancel := context.WithCancel(context.Background())
+
+		dests := FanOutContext(ctx, Generator(ctx), n)
+
+		for i := 0; i < 5; i++ {
+			<-dests[0]
+		}
+
+		// Nobody reads the destinations anymore, so the goroutines are blocked on sending.
+		cancel()
+
+		for _, dest := range dests {
+			for range dest {
+			}
+		}
+	})
+}
+
+// collectDests reads all the destinations concurrently until they are closed.
+func collectDests[T any](dests []<-chan T) [][]T {
+	var wg sync.WaitGroup
+
+	res := make([][]T, len(dests))
+	wg.Add(len(dests))
+
+	for i, dest := range dests {
+		go func(i int, dest <-chan T) {
+			defer wg.Done()
+
+			for val := range dest {
+				res[i] = append(res[i], val)
+			}
+		}(i, dest)
+	}
+
+	wg.Wait()
+
+	return res
+}
+
+func TestFanOutWith(t *testing.T) {
+	ctx := context.Background()
+	vals := []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+	t.Run("round-robin", func(t *testing.T) {
+		got := collectDests(FanOutWith(ctx, sliceChan(vals, 0), 3, RoundRobin[int]()))
+		want := [][]int{{0, 3, 6}, {1, 4, 7}, {2, 5}}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong fan out result: got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("key affinity", func(t *testing.T) {
+		parity := KeyAffinity(func(val int) string { return fmt.Sprint(val % 2) })
+		got := collectDests(FanOutWith(ctx, sliceChan(vals, 0), 4, parity))
+
+		for i, dest := range got {
+			for _, val := range dest {
+				if val%2 != dest[0]%2 {
+					t.Errorf("destination %d got values with different keys: %v", i, dest)
+				}
+			}
+		}
+	})
+
+	t.Run("broadcast", func(t *testing.T) {
+		got := collectDests(FanOutWith(ctx, sliceChan(vals, 0), 3, Broadcast[int]()))
+		want := [][]int{vals, vals, vals}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong fan out result: got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("competing by default", func(t *testing.T) {
+		var got []int
+		for _, dest := range collectDests(FanOutWith(ctx, sliceChan(vals, 0), 3, nil)) {
+			got = append(got, dest...)
+		}
+
+		sort.Ints(got)
+
+		if !reflect.DeepEqual(got, vals) {
+			t.Errorf("wrong fan out result: got %v, want %v", got, vals)
+		}
+	})
+}
+
+func TestFanOutWithCancel(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		dests := FanOutWith(ctx, Generator(ctx), 3, Broadcast[int]())
+		<-dests[0]
+
+		cancel()
+
+		for _, dest := range dests {
+			for range dest {
+			}
+		}
+	})
+}
+
+func TestFanOutWithNegativeBufferSize(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		src := make(chan int)
+		dests := FanOutWith(context.Background(), src, 2, RoundRobin[int](), WithBufferSize(-1))
+
+		for _, dest := range dests {
+			if n := cap(dest); n != 0 {
+				t.Errorf("wrong buffer size: got %d, want 0", n)
+			}
+		}
+
+		close(src)
+
+		for _, dest := range dests {
+			for range dest {
+			}
+		}
+	})
+}
+
+func TestFanOutWithNoDestinations(t *testing.T) {
+	distributors := map[string]Distributor[int]{
+		"competing":    nil,
+		"round robin":  RoundRobin[int](),
+		"least loaded": LeastLoaded[int](),
+		"key affinity": KeyAffinity(func(val int) string { return fmt.Sprint(val) }),
+	}
+
+	for name, d := range distributors {
+		d := d
+
+		t.Run(name, func(t *testing.T) {
+			testutils.DetectGoroutineLeeks(t, func() {
+				for _, n := range []int{0, -1} {
+					if dests := FanOutWith(context.Background(), make(chan int), n, d); len(dests) != 0 {
+						t.Errorf("wrong number of destinations for n = %d: got %d, want 0", n, len(dests))
+					}
+				}
+			})
+		})
+	}
+}
+
+// feed sends the values to the channel and returns when the last one is received,
+// so all the values but the last one are already distributed by FanOutWith.
+func feed(src chan<- int, vals ...int) {
+	for _, val := range vals {
+		src <- val
+	}
+}
+
+func TestFanOutWithOverflow(t *testing.T) {
+	const bufSize = 2
+
+	ctx := context.Background()
+	first := DistributorFunc[int](func(int, []int) []int { return []int{0} })
+
+	testCases := []struct {
+		name         string
+		n            int
+		d            Distributor[int]
+		opts         []FanOutOption
+		want         [][]int
+		wantDropped  int64
+		wantRerouted int64
+	}{
+		{
+			name: "block",
+			n:    2,
+			d:    RoundRobin[int](),
+			opts: []FanOutOption{WithBufferSize(bufSize)},
+			want: [][]int{{0, 2, 4}, {1, 3}},
+		},
+		{
+			name:        "drop newest",
+			n:           1,
+			d:           first,
+			opts:        []FanOutOption{WithBufferSize(bufSize), WithOverflowPolicy(OverflowDropNewest)},
+			want:        [][]int{{0, 1}},
+			wantDropped: 3,
+		},
+		{
+			name:        "drop oldest",
+			n:           1,
+			d:           first,
+			opts:        []FanOutOption{WithBufferSize(bufSize), WithOverflowPolicy(OverflowDropOldest)},
+			want:        [][]int{{3, 4}},
+			wantDropped: 3,
+		},
+		{
+			name:         "reroute",
+			n:            3,
+			d:            first,
+			opts:         []FanOutOption{WithBufferSize(bufSize), WithOverflowPolicy(OverflowReroute)},
+			want:         [][]int{{0, 1}, {2, 3}, {4}},
+			wantRerouted: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stats FanOutStats
+
+			src := make(chan int)
+			dests := FanOutWith(ctx, src, tc.n, tc.d, append(tc.opts, WithFanOutStats(&stats))...)
+
+			// Nobody reads the destinations until all the values are distributed: every destination
+			// holds as many values as its buffer fits, and all the drops and reroutes are counted.
+			// With the block policy, the last value waits for room, which doesn't change the buffers.
+			feed(src, 0, 1, 2, 3, 4)
+			close(src)
+
+			var buffered int
+			for _, vals := range tc.want {
+				if len(vals) < bufSize {
+					buffered += len(vals)
+				} else {
+					buffered += bufSize
+				}
+			}
+
+			waitFor(t, time.Second, func() bool {
+				var n int
+				for _, dest := range dests {
+					n += len(dest)
+				}
+
+				return n == buffered && stats.Dropped() == tc.wantDropped && stats.Rerouted() == tc.wantRerouted
+			})
+
+			got := collectDests(dests)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("wrong fan out result: got %v, want %v", got, tc.want)
+			}
+
+			if stats.Dropped() != tc.wantDropped {
+				t.Errorf("wrong number of dropped values: got %d, want %d", stats.Dropped(), tc.wantDropped)
+			}
+
+			if stats.Rerouted() != tc.wantRerouted {
+				t.Errorf("wrong number of rerouted values: got %d, want %d", stats.Rerouted(), tc.wantRerouted)
+			}
+		})
+	}
+}
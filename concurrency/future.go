@@ -27,56 +27,130 @@ import (
 // as a result, calls of ConcurrentInverse will be executed serially, requiring twice the runtime.
 // Future pattern encapsulates this complexity in an API that provides the consumer with a simple interface
 // whose method can be called normally.
-type Future interface {
+type Future[T any] interface {
 	// Result returns the result blocking the call until the result is ready.
-	Result() (string, error)
+	Result() (T, error)
+	// ResultContext is the same as Result, but it stops waiting and returns the context error
+	// when the context is done. The asynchronous process isn't canceled in this case.
+	ResultContext(ctx context.Context) (T, error)
+	// Done returns a channel that's closed when the result is ready.
+	Done() <-chan struct{}
+	// Cancel cancels the asynchronous process. If the result isn't ready yet,
+	// it becomes context.Canceled error.
+	Cancel()
 }
 
 // InnerFuture implements Future.
-// It retrieves ready values from the channels, caches and returns them. If the values aren’t available
-// on the channels, the request blocks. If they have already been retrieved, the cached values are returned.
-type InnerFuture struct {
-	once     sync.Once
-	wg       sync.WaitGroup
-	result   string
-	err      error
-	resultCh <-chan string
-	errCh    <-chan error
+// The asynchronous process completes it only once, saving the result and closing the done channel.
+// Until then, the calls to retrieve the result block. After that, they return the saved values
+// straightaway. Completing InnerFuture also cancels the context of the process, so the goroutine
+// watching the context is released even if the parent context is never canceled.
+type InnerFuture[T any] struct {
+	once   sync.Once
+	done   chan struct{}
+	result T
+	err    error
+	cancel context.CancelFunc
 }
 
-func (f *InnerFuture) Result() (string, error) {
-	f.once.Do(func() {
-		f.wg.Add(1)
-		defer f.wg.Done()
+func newInnerFuture[T any](cancel context.CancelFunc) *InnerFuture[T] {
+	return &InnerFuture[T]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+}
+
+func (f *InnerFuture[T]) Result() (T, error) {
+	<-f.done
+
+	return f.result, f.err
+}
+
+func (f *InnerFuture[T]) ResultContext(ctx context.Context) (T, error) {
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case <-f.done:
+		return f.result, f.err
+	}
+}
+
+func (f *InnerFuture[T]) Done() <-chan struct{} {
+	return f.done
+}
 
-		f.result, f.err = <-f.resultCh, <-f.errCh
+func (f *InnerFuture[T]) Cancel() {
+	var zero T
+	f.complete(zero, context.Canceled)
+}
+
+// complete saves the result if it isn't saved yet. It reports whether the result is saved by this call.
+func (f *InnerFuture[T]) complete(result T, err error) bool {
+	completed := false
+
+	f.once.Do(func() {
+		f.result, f.err = result, err
+		completed = true
+		close(f.done)
 	})
 
-	f.wg.Wait()
+	f.cancel()
 
-	return f.result, f.err
+	return completed
 }
 
 // SlowFunc is some kind of blocking function that should be performed asynchronously.
 type SlowFunc func() (string, error)
 
 // RunAsync is a wrapper function that asynchronously performs SlowFunc and returns Future.
-func RunAsync(ctx context.Context, fn SlowFunc) Future {
-	resultCh := make(chan string, 1)
-	errCh := make(chan error, 1)
+// By default, every call spawns its own goroutine, so a burst of calls spawns the unbounded
+// number of them. WithExecutor option makes RunAsync perform the function on Executor instead,
+// which bounds the number of goroutines.
+func RunAsync(ctx context.Context, fn SlowFunc, opts ...AsyncOption) Future[string] {
+	return Async(ctx, func(context.Context) (string, error) {
+		return fn()
+	}, opts...)
+}
 
-	go func() {
-		result, err := fn()
-		resultCh <- result
-		errCh <- err
-	}()
+type asyncConfig struct {
+	executor *Executor
+}
 
-	go func() {
-		<-ctx.Done()
+// AsyncOption configures RunAsync and Async.
+type AsyncOption func(cfg *asyncConfig)
 
-		resultCh <- ""
-		errCh <- ctx.Err()
+// WithExecutor makes RunAsync and Async submit the function to the Executor rather than spawn
+// a goroutine for it. It's the same as calling Submit directly.
+func WithExecutor(e *Executor) AsyncOption {
+	return func(cfg *asyncConfig) {
+		cfg.executor = e
+	}
+}
+
+// AsyncFunc is a blocking function producing a value of type T that should be performed asynchronously.
+// It should stop as soon as possible when the context is done.
+type AsyncFunc[T any] func(ctx context.Context) (T, error)
+
+// Async is the generic version of RunAsync. It asynchronously performs AsyncFunc and returns Future.
+// If the context is done or the Future is canceled before the function returns, the result
+// is the context error, and the function's result is discarded.
+func Async[T any](ctx context.Context, fn AsyncFunc[T], opts ...AsyncOption) Future[T] {
+	var cfg asyncConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.executor != nil {
+		return Submit(ctx, cfg.executor, fn)
+	}
+
+	p, ctx := newPromise[T](ctx)
+
+	go func() {
+		result, err := fn(ctx)
+		p.future.complete(result, err)
 	}()
 
-	return &InnerFuture{resultCh: resultCh, errCh: errCh}
+	return p.future
 }
@@ -7,6 +7,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
 )
 
 func slowFunction() (string, error) {
@@ -163,6 +165,75 @@ func TestFutureCancel(t *testing.T) {
 	elapsedCheck(t, start, 1)
 }
 
+// TestFutureCancelMethod makes sure that Cancel makes the result ready straightaway.
+func TestFutureCancelMethod(t *testing.T) {
+	start := time.Now()
+	future := RunAsync(context.Background(), slowFunction)
+
+	go func() {
+		time.Sleep(time.Second)
+		future.Cancel()
+	}()
+
+	res, err := future.Result()
+	if !errors.Is(err, context.Canceled) {
+		t.Error("received unexpected error: ", err)
+	}
+
+	if res != "" {
+		t.Error("should have an empty result")
+	}
+
+	elapsedCheck(t, start, 1)
+}
+
+// TestFutureResultContext makes sure that ResultContext stops waiting when its context
+// is done, but it doesn't affect the future itself.
+func TestFutureResultContext(t *testing.T) {
+	future := Async(context.Background(), func(ctx context.Context) (int, error) {
+		time.Sleep(100 * time.Millisecond)
+		return 42, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := future.ResultContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("received unexpected error: ", err)
+	}
+
+	select {
+	case <-future.Done():
+		t.Error("future is done too early")
+	default:
+	}
+
+	res, err := future.ResultContext(context.Background())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if res != 42 {
+		t.Errorf("wrong result: got %d, want 42", res)
+	}
+}
+
+// TestFutureReleasesGoroutines makes sure that no goroutine is left after the result
+// is ready, even though the context is never canceled.
+func TestFutureReleasesGoroutines(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		future := Async(context.Background(), func(ctx context.Context) (string, error) {
+			return "done", nil
+		})
+
+		<-future.Done()
+
+		if res, err := future.Result(); res != "done" || err != nil {
+			t.Errorf("wrong result: got (%q, %v), want (\"done\", nil)", res, err)
+		}
+	})
+}
+
 func elapsedCheck(t *testing.T, start time.Time, seconds int) {
 	elapsed := int(time.Since(start).Seconds())
 
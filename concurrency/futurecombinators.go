@@ -0,0 +1,148 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoFutures is returned by the combinators that have to pick a result
+// among the futures, but none is given.
+var ErrNoFutures = errors.New("no futures to wait for")
+
+// The combinators below compose futures into a new Future. Each of them performs
+// the waiting asynchronously with Async, so the returned Future stops waiting and fails with
+// the context error when the context is done or the returned Future is canceled.
+// The input futures aren't canceled in this case, since they may have other consumers.
+
+// All returns Future that gathers the results of all the futures in the order of arguments.
+// It fails as soon as any of the futures fails, with the error of the first future
+// that has completed with an error.
+func All[T any](ctx context.Context, futures ...Future[T]) Future[[]T] {
+	return Async(ctx, func(ctx context.Context) ([]T, error) {
+		results := make([]T, len(futures))
+		completed := watchFutures(ctx, futures)
+
+		for range futures {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case i := <-completed:
+				res, err := futures[i].Result()
+				if err != nil {
+					return nil, err
+				}
+
+				results[i] = res
+			}
+		}
+
+		return results, nil
+	})
+}
+
+// Any returns Future that resolves to the result of the first future that succeeds.
+// If all the futures fail, it fails with the errors of all of them joined in the order of arguments.
+func Any[T any](ctx context.Context, futures ...Future[T]) Future[T] {
+	return Async(ctx, func(ctx context.Context) (T, error) {
+		var zero T
+		if len(futures) == 0 {
+			return zero, ErrNoFutures
+		}
+
+		errs := make([]error, len(futures))
+		completed := watchFutures(ctx, futures)
+
+		for range futures {
+			select {
+			case <-ctx.Done():
+				return zero, ctx.Err()
+			case i := <-completed:
+				res, err := futures[i].Result()
+				if err == nil {
+					return res, nil
+				}
+
+				errs[i] = err
+			}
+		}
+
+		return zero, errors.Join(errs...)
+	})
+}
+
+// Race returns Future that settles with the result or the error of the first future that completes.
+func Race[T any](ctx context.Context, futures ...Future[T]) Future[T] {
+	return Async(ctx, func(ctx context.Context) (T, error) {
+		var zero T
+		if len(futures) == 0 {
+			return zero, ErrNoFutures
+		}
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case i := <-watchFutures(ctx, futures):
+			return futures[i].Result()
+		}
+	})
+}
+
+// Then returns Future that chains fn after the future: fn is called with the result of the future
+// once it succeeds. If the future fails, fn isn't called and the error is propagated as is.
+func Then[T, U any](ctx context.Context, f Future[T], fn func(ctx context.Context, val T) (U, error)) Future[U] {
+	return Async(ctx, func(ctx context.Context) (U, error) {
+		val, err := f.ResultContext(ctx)
+		if err != nil {
+			var zero U
+			return zero, err
+		}
+
+		return fn(ctx, val)
+	})
+}
+
+// Map is a simplified Then for the transformations that can't fail.
+func Map[T, U any](ctx context.Context, f Future[T], fn func(val T) U) Future[U] {
+	return Then(ctx, f, func(_ context.Context, val T) (U, error) {
+		return fn(val), nil
+	})
+}
+
+// Recover returns Future that handles the error of the future: if the future fails, its result
+// is replaced by what fn returns for the error. The successful result is passed through as is.
+// A failure caused by the context of Recover itself isn't handled.
+func Recover[T any](ctx context.Context, f Future[T], fn func(err error) (T, error)) Future[T] {
+	return Async(ctx, func(ctx context.Context) (T, error) {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-f.Done():
+		}
+
+		val, err := f.Result()
+		if err != nil {
+			return fn(err)
+		}
+
+		return val, nil
+	})
+}
+
+// watchFutures returns the channel that receives the indexes of the futures in order
+// they complete. The watching goroutines exit when the context is done.
+func watchFutures[T any](ctx context.Context, futures []Future[T]) <-chan int {
+	completed := make(chan int, len(futures))
+
+	for i, f := range futures {
+		go func(i int, f Future[T]) {
+			select {
+			case <-ctx.Done():
+			case <-f.Done():
+				completed <- i
+			}
+		}(i, f)
+	}
+
+	return completed
+}
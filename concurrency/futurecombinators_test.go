@@ -0,0 +1,173 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+// delayed returns Future that settles with the given result after the delay.
+func delayed[T any](d time.Duration, val T, err error) Future[T] {
+	return Async(context.Background(), func(ctx context.Context) (T, error) {
+		time.Sleep(d)
+		return val, err
+	})
+}
+
+func TestAll(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		res, err := All(ctx,
+			delayed(30*time.Millisecond, 1, nil),
+			delayed(10*time.Millisecond, 2, nil),
+			delayed(20*time.Millisecond, 3, nil),
+		).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []int{1, 2, 3}; !reflect.DeepEqual(res, want) {
+			t.Errorf("wrong result: got %v, want %v", res, want)
+		}
+	})
+
+	t.Run("first failure", func(t *testing.T) {
+		errFirst, errSecond := errors.New("first"), errors.New("second")
+		start := time.Now()
+
+		_, err := All(ctx,
+			delayed(time.Second, 1, nil),
+			delayed(20*time.Millisecond, 0, errSecond),
+			delayed(10*time.Millisecond, 0, errFirst),
+		).Result()
+		if !errors.Is(err, errFirst) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("All didn't fail fast: took %v", elapsed)
+		}
+	})
+}
+
+func TestAny(t *testing.T) {
+	ctx := context.Background()
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+
+	res, err := Any(ctx,
+		delayed(10*time.Millisecond, 0, errFirst),
+		delayed(30*time.Millisecond, 2, nil),
+		delayed(20*time.Millisecond, 3, nil),
+	).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != 3 {
+		t.Errorf("wrong result: got %d, want 3", res)
+	}
+
+	_, err = Any(ctx,
+		delayed(20*time.Millisecond, 0, errFirst),
+		delayed(10*time.Millisecond, 0, errSecond),
+	).Result()
+	if want := "first\nsecond"; err == nil || err.Error() != want {
+		t.Errorf("wrong error: got %v, want %q", err, want)
+	}
+
+	if _, err = Any[int](ctx).Result(); !errors.Is(err, ErrNoFutures) {
+		t.Errorf("received unexpected error: %v", err)
+	}
+}
+
+func TestRace(t *testing.T) {
+	ctx := context.Background()
+	errFast := errors.New("fast")
+
+	_, err := Race(ctx,
+		delayed(30*time.Millisecond, 1, nil),
+		delayed(10*time.Millisecond, 0, errFast),
+	).Result()
+	if !errors.Is(err, errFast) {
+		t.Errorf("received unexpected error: %v", err)
+	}
+}
+
+func TestThenMapRecover(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	square := func(_ context.Context, val int) (int, error) {
+		return val * val, nil
+	}
+	describe := func(val int) string {
+		if val > 10 {
+			return "big"
+		}
+
+		return "small"
+	}
+
+	res, err := Map(ctx, Then(ctx, delayed(10*time.Millisecond, 5, nil), square), describe).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res != "big" {
+		t.Errorf("wrong result: got %s, want big", res)
+	}
+
+	called := false
+	failed := Then(ctx, delayed(10*time.Millisecond, 0, errFailed), func(ctx context.Context, val int) (int, error) {
+		called = true
+		return val, nil
+	})
+
+	recovered := Recover(ctx, failed, func(err error) (int, error) {
+		if !errors.Is(err, errFailed) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		return -1, nil
+	})
+
+	val, err := recovered.Result()
+	if err != nil || val != -1 {
+		t.Errorf("wrong result: got (%d, %v), want (-1, nil)", val, err)
+	}
+
+	if called {
+		t.Error("Then called the function after the failure")
+	}
+}
+
+func TestCombinatorCancel(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := Async(ctx, func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+
+		all := All(context.Background(), slow, slow)
+		all.Cancel()
+
+		if _, err := all.Result(); !errors.Is(err, context.Canceled) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		select {
+		case <-slow.Done():
+			t.Error("canceling the combined future mustn't cancel the inputs")
+		default:
+		}
+
+		cancel()
+		<-slow.Done()
+	})
+}
@@ -4,16 +4,43 @@ import "context"
 
 // Generator is used to generate the sequence of values. It simply returns a channel
 // from which we can read the values. This is a similar behavior as yield in JavaScript and Python.
+//
+// Generator emits 0, 1, 2, ... until the context is done, see GeneratorOf to produce
+// values of any other type.
 func Generator(ctx context.Context) <-chan int {
-	ch := make(chan int)
+	var i int
+
+	return GeneratorOf(ctx, func() (int, bool) {
+		val := i
+		i++
+
+		return val, true
+	})
+}
+
+// GeneratorOf is the generic version of Generator. It calls the producer function
+// to get the next value of the sequence and sends it to the returned channel. The channel
+// is closed when the context is done or the producer reports that the sequence is
+// exhausted by returning false.
+//
+// The producer is called from a single goroutine, so it's safe to keep the state
+// of the sequence in its closure.
+func GeneratorOf[T any](ctx context.Context, next func() (T, bool)) <-chan T {
+	ch := make(chan T)
 
 	go func() {
-		for i := 0; ; i++ {
+		defer close(ch)
+
+		for {
+			val, ok := next()
+			if !ok {
+				return
+			}
+
 			select {
 			case <-ctx.Done():
-				close(ch)
 				return
-			case ch <- i:
+			case ch <- val:
 			}
 		}
 	}()
@@ -2,6 +2,7 @@ package concurrency
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -40,3 +41,28 @@ func TestGenerator(t *testing.T) {
 		t.Errorf("generator isn't closed when context done")
 	}
 }
+
+func TestGeneratorOf(t *testing.T) {
+	words := []string{"alpha", "beta", "gamma"}
+
+	var i int
+	genCh := GeneratorOf(context.Background(), func() (string, bool) {
+		if i == len(words) {
+			return "", false
+		}
+
+		w := words[i]
+		i++
+
+		return w, true
+	})
+
+	var got []string
+	for val := range genCh {
+		got = append(got, val)
+	}
+
+	if !reflect.DeepEqual(got, words) {
+		t.Errorf("wrong result: got %v, want %v", got, words)
+	}
+}
@@ -0,0 +1,105 @@
+package concurrency
+
+import "context"
+
+// Iterator adapts the channel of a producer, such as Generator or Pipeline, to the pull-style
+// iteration. Instead of the raw channel loops where it's easy to forget stopping the producer,
+// the values are pulled by Next, and Close guarantees that the producer is stopped:
+//
+//	it := Iterate(ctx, Generator)
+//	defer it.Close()
+//
+//	for val, ok := it.Next(ctx); ok; val, ok = it.Next(ctx) {
+//		// ...
+//	}
+//
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+//
+// Iterator isn't safe for concurrent use.
+type Iterator[T any] struct {
+	ch       <-chan T
+	stop     func()
+	ctx      context.Context
+	err      error
+	finished bool
+	closed   bool
+}
+
+// NewIterator constructs Iterator over the channel. The stop function is called by Close,
+// it must make the producer close the channel, e.g. cancel the producer's context or close
+// the input of Pipeline.
+func NewIterator[T any](ch <-chan T, stop func()) *Iterator[T] {
+	return &Iterator[T]{ch: ch, stop: stop}
+}
+
+// Iterate starts the producer with the context derived from the given one
+// and returns Iterator over its channel. Close cancels the producer's context.
+func Iterate[T any](ctx context.Context, start func(ctx context.Context) <-chan T) *Iterator[T] {
+	ctx, cancel := context.WithCancel(ctx)
+
+	it := NewIterator(start(ctx), cancel)
+	it.ctx = ctx
+
+	return it
+}
+
+// Next returns the next value blocking until the producer sends it. It returns false when
+// the iteration is over: the producer has closed the channel, the context is done or Iterator is closed.
+// Once Next returns false, the subsequent calls return false straightaway.
+func (it *Iterator[T]) Next(ctx context.Context) (T, bool) {
+	var zero T
+	if it.finished {
+		return zero, false
+	}
+
+	select {
+	case <-ctx.Done():
+		it.finish(ctx.Err())
+		return zero, false
+	case val, ok := <-it.ch:
+		if !ok {
+			var err error
+			if it.ctx != nil {
+				err = it.ctx.Err()
+			}
+
+			it.finish(err)
+
+			return zero, false
+		}
+
+		return val, true
+	}
+}
+
+// Err returns the error that has finished the iteration. It's nil if the producer
+// has closed the channel on its own or Iterator is closed.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Close stops the producer and waits until it closes the channel, discarding
+// the values that are not pulled yet. It's safe to call Close several times.
+func (it *Iterator[T]) Close() {
+	if it.closed {
+		return
+	}
+
+	it.closed = true
+	it.finished = true
+	it.stop()
+
+	for range it.ch {
+	}
+}
+
+func (it *Iterator[T]) finish(err error) {
+	if it.finished {
+		return
+	}
+
+	it.finished = true
+	it.err = err
+}
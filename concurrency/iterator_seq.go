@@ -0,0 +1,27 @@
+//go:build go1.23
+
+package concurrency
+
+import (
+	"context"
+	"iter"
+)
+
+// Seq adapts Iterator to iter.Seq, so it can be used in the range loop:
+//
+//	for val := range it.Seq(ctx) {
+//		// ...
+//	}
+//
+// Iterator is closed when the loop is over, even if it's left with break.
+func (it *Iterator[T]) Seq(ctx context.Context) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer it.Close()
+
+		for val, ok := it.Next(ctx); ok; val, ok = it.Next(ctx) {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build go1.23
+
+package concurrency
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func TestIteratorSeq(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		ctx := context.Background()
+		it := Iterate(ctx, Generator)
+
+		var got int
+		for val := range it.Seq(ctx) {
+			if val == 5 {
+				break
+			}
+
+			got += val
+		}
+
+		if want := 0 + 1 + 2 + 3 + 4; got != want {
+			t.Errorf("wrong result: got %d, want %d", got, want)
+		}
+
+		if _, ok := it.Next(ctx); ok {
+			t.Error("iterator isn't closed after the loop")
+		}
+	})
+}
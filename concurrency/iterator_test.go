@@ -0,0 +1,112 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func TestIterator(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		ctx := context.Background()
+		it := Iterate(ctx, Generator)
+
+		var got []int
+		for val, ok := it.Next(ctx); ok && val < 3; val, ok = it.Next(ctx) {
+			got = append(got, val)
+		}
+
+		// The producer is still running here, Close must stop it.
+		it.Close()
+		it.Close()
+
+		if want := []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong result: got %v, want %v", got, want)
+		}
+
+		if _, ok := it.Next(ctx); ok {
+			t.Error("closed iterator returned the value")
+		}
+
+		if err := it.Err(); err != nil {
+			t.Errorf("received unexpected error: %v", err)
+		}
+	})
+}
+
+func TestIteratorErr(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		parent, cancel := context.WithCancel(context.Background())
+		it := Iterate(parent, Generator)
+
+		cancel()
+
+		for _, ok := it.Next(context.Background()); ok; _, ok = it.Next(context.Background()) {
+		}
+
+		if err := it.Err(); !errors.Is(err, context.Canceled) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+
+		it.Close()
+
+		// The context of Next only stops waiting for the next value.
+		timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancelTimeout()
+
+		blocked := Iterate(context.Background(), func(ctx context.Context) <-chan int {
+			out := make(chan int)
+
+			go func() {
+				<-ctx.Done()
+				close(out)
+			}()
+
+			return out
+		})
+		defer blocked.Close()
+
+		if _, ok := blocked.Next(timeoutCtx); ok {
+			t.Error("expected no value")
+		}
+
+		if err := blocked.Err(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+	})
+}
+
+func TestIteratorPipeline(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		ctx := context.Background()
+
+		in, out := Pipeline(incrJob, incrJob)
+		it := NewIterator(out, func() { close(in) })
+
+		go func() {
+			for i := 0; i < 3; i++ {
+				in <- i
+			}
+		}()
+
+		var got []any
+		for i := 0; i < 3; i++ {
+			val, ok := it.Next(ctx)
+			if !ok {
+				t.Fatal("pipeline is closed too early")
+			}
+
+			got = append(got, val)
+		}
+
+		it.Close()
+
+		if want := []any{2, 3, 4}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong result: got %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package concurrency
+
+// WithKey makes Pool serialize the values with the same key: they are processed strictly
+// in the order they are put into the pool, one after another, while the values with different
+// keys are still processed in parallel. This is the actor-per-key pattern, where every key
+// is an actor with its own mailbox.
+//
+// Only one value of a key waits in the queue or is processed at a time, the following values
+// of the key wait in its backlog. The worker that processes a value of the key takes the next
+// one from the backlog as soon as it's done, so a busy key keeps a single worker until its backlog
+// is drained. With WithPriorities, the priorities order the keys, not the values of one key.
+//
+// Every key's backlog has its own limit set by WithKeyBacklog, separate from the queue, so a busy key
+// doesn't take the room of the other keys: Submit for a key with the full backlog waits, while
+// the values of the other keys get in. The input channel is a single FIFO though, so the value
+// waiting for room in its key's backlog holds back the values sent after it. Use Submit if the keys
+// must not hold back each other at all.
+func WithKey(key func(val any) string) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.key = key
+	}
+}
+
+// WithKeyBacklog sets how many values of a single key may wait for the key's current value
+// to be processed. Default is the queue size.
+func WithKeyBacklog(n int) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.keyBacklog = n
+	}
+}
+
+// keyOf computes the key of the value if the values are keyed.
+func (p *Pool) keyOf(val any) string {
+	if p.cfg.key == nil {
+		return ""
+	}
+
+	return p.cfg.key(val)
+}
+
+// backlog puts the task into its key's backlog if the key already has a value queued
+// or being processed. It reports whether the task is backlogged, otherwise the task
+// has to be queued.
+func (p *Pool) backlog(task *poolTask) bool {
+	backlog, busy := p.keys[task.key]
+	if !busy {
+		p.keys[task.key] = nil
+		return false
+	}
+
+	p.keys[task.key] = append(backlog, *task)
+	p.backlogged++
+
+	return true
+}
+
+// nextOfKey takes the next task from the key's backlog. If the backlog is empty,
+// the key is released, so its next value goes into the queue.
+func (p *Pool) nextOfKey(key string) (poolTask, bool) {
+	backlog := p.keys[key]
+	if len(backlog) == 0 {
+		delete(p.keys, key)
+		return poolTask{}, false
+	}
+
+	task := backlog[0]
+	backlog[0] = poolTask{}
+	p.keys[key] = backlog[1:]
+	p.backlogged--
+	p.take(task)
+
+	return task, true
+}
@@ -0,0 +1,256 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+// keyedValue is the value of the keyed pool.
+type keyedValue struct {
+	key string
+	seq int
+}
+
+func keyOf(val any) string {
+	return val.(keyedValue).key
+}
+
+func TestPoolKeyed(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		const (
+			keys    = 4
+			perKey  = 10
+			workers = 4
+		)
+
+		var (
+			mu      sync.Mutex
+			applied = make(map[string][]int)
+			running = make(map[string]int)
+			meter   concurrencyMeter
+		)
+
+		p := NewPool(workers, meter.wrap(func(in any) any {
+			val := in.(keyedValue)
+
+			mu.Lock()
+			running[val.key]++
+			if running[val.key] > 1 {
+				t.Errorf("values of key %q are processed concurrently", val.key)
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+
+			mu.Lock()
+			running[val.key]--
+			applied[val.key] = append(applied[val.key], val.seq)
+			mu.Unlock()
+
+			return in
+		}), WithKey(keyOf), WithQueueSize(8))
+
+		drained := drain(p.Out())
+
+		// The values of all keys are interleaved. The values sent to the input channel and the submitted
+		// ones aren't ordered with each other, so half of the keys use each way.
+		var futures []Future[any]
+
+		for seq := 0; seq < perKey; seq++ {
+			for k := 0; k < keys; k++ {
+				val := keyedValue{key: fmt.Sprintf("key-%d", k), seq: seq}
+
+				if k%2 == 0 {
+					p.In() <- val
+				} else {
+					futures = append(futures, p.Submit(context.Background(), val))
+				}
+			}
+		}
+
+		for _, f := range futures {
+			if _, err := f.Result(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		close(p.In())
+		<-drained
+
+		want := make([]int, perKey)
+		for i := range want {
+			want[i] = i
+		}
+
+		for k := 0; k < keys; k++ {
+			key := fmt.Sprintf("key-%d", k)
+			if got := applied[key]; !reflect.DeepEqual(got, want) {
+				t.Errorf("wrong order of key %q: got %v, want %v", key, got, want)
+			}
+		}
+
+		if max := atomic.LoadInt64(&meter.max); max < 2 {
+			t.Errorf("different keys aren't processed in parallel: max concurrency %d", max)
+		}
+	})
+}
+
+func TestPoolKeyedStop(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		started := make(chan struct{}, 1)
+
+		p := NewContextPool(2, func(ctx context.Context, in any) any {
+			started <- struct{}{}
+			<-ctx.Done()
+
+			return in
+		}, WithKey(keyOf), WithQueueSize(4))
+
+		ctx := context.Background()
+
+		inFlight := p.Submit(ctx, keyedValue{key: "a", seq: 0})
+		<-started
+
+		// The values of the busy key wait in the backlog even though a worker is free.
+		backlogged := []Future[any]{
+			p.Submit(ctx, keyedValue{key: "a", seq: 1}),
+			p.Submit(ctx, keyedValue{key: "a", seq: 2}),
+		}
+
+		select {
+		case <-started:
+			t.Error("value of the busy key is processed by another worker")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		p.Stop()
+
+		if _, err := inFlight.Result(); !errors.Is(err, context.Canceled) {
+			t.Errorf("wrong error of the in-flight submission: got %v, want %v", err, context.Canceled)
+		}
+
+		for _, f := range backlogged {
+			if _, err := f.Result(); !errors.Is(err, ErrPoolStopped) {
+				t.Errorf("wrong error of the backlogged submission: got %v, want %v", err, ErrPoolStopped)
+			}
+		}
+
+		<-drain(p.Out())
+	})
+}
+
+func TestPoolKeyedAutoscale(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		release := make(chan struct{})
+		started := make(chan struct{}, 1)
+
+		p := NewPool(1, func(in any) any {
+			started <- struct{}{}
+			<-release
+
+			return in
+		}, WithKey(keyOf), WithKeyBacklog(2), WithAutoscale(AutoscaleConfig{
+			Min:         1,
+			Max:         4,
+			Interval:    5 * time.Millisecond,
+			IdleTimeout: time.Minute,
+		}))
+
+		drained := drain(p.Out())
+		ctx := context.Background()
+
+		futures := []Future[any]{p.Submit(ctx, keyedValue{key: "a", seq: 0})}
+		<-started
+
+		// The queue stays empty, the values of the busy key wait in its backlog.
+		futures = append(futures,
+			p.Submit(ctx, keyedValue{key: "a", seq: 1}),
+			p.Submit(ctx, keyedValue{key: "a", seq: 2}),
+		)
+
+		waitFor(t, time.Second, func() bool { return p.Size() == 3 })
+
+		close(release)
+
+		for i := 1; i < len(futures); i++ {
+			<-started
+		}
+
+		for _, f := range futures {
+			if _, err := f.Result(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+
+		close(p.In())
+		<-drained
+	})
+}
+
+func TestPoolKeyedBusyKeyDoesntBlockOthers(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		release := make(chan struct{})
+		started := make(chan struct{})
+
+		p := NewPool(4, func(in any) any {
+			if in.(keyedValue).key == "hot" {
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+
+				<-release
+			}
+
+			return in
+		}, WithKey(keyOf))
+
+		drained := drain(p.Out())
+		ctx := context.Background()
+
+		hot := []Future[any]{p.Submit(ctx, keyedValue{key: "hot", seq: 0})}
+		<-started
+
+		// The hot key's backlog is full with the default limit of one value.
+		hot = append(hot, p.Submit(ctx, keyedValue{key: "hot", seq: 1}))
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		if _, err := p.Submit(timeoutCtx, keyedValue{key: "hot", seq: 2}).Result(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("wrong error of the submission to the full backlog: got %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		// The other keys still get in and are processed by the idle workers.
+		for i := 0; i < 6; i++ {
+			resCtx, resCancel := context.WithTimeout(ctx, time.Second)
+			val := keyedValue{key: fmt.Sprintf("idle-%d", i)}
+
+			if _, err := p.Submit(resCtx, val).ResultContext(resCtx); err != nil {
+				t.Errorf("value of the idle key %q isn't processed: %v", val.key, err)
+			}
+
+			resCancel()
+		}
+
+		close(release)
+
+		for _, f := range hot {
+			if _, err := f.Result(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}
+
+		close(p.In())
+		<-drained
+	})
+}
@@ -0,0 +1,111 @@
+package concurrency
+
+import (
+	"container/heap"
+	"context"
+)
+
+// OrderedFanIn is a FanIn variant that merges sources, each of them is already sorted,
+// into one globally sorted destination channel. The order is defined by the less
+// function, the values that are equal are taken in the order of their sources.
+//
+// OrderedFanIn performs k-way merge using a min-heap that holds the head value of
+// every source. To decide which value goes next it has to know the heads of all the
+// sources, so it waits until each not yet closed source provides a value. The sources
+// may close at different times, the closed ones are just removed from the merge.
+// The destination channel is closed when all the sources are drained or the context is done.
+func OrderedFanIn[T any](ctx context.Context, less func(a, b T) bool, sources ...<-chan T) <-chan T {
+	dest := make(chan T)
+
+	go func() {
+		defer close(dest)
+
+		h := &mergeHeap[T]{less: less}
+
+		for i, src := range sources {
+			val, ok, done := recvContext(ctx, src)
+			if done {
+				return
+			}
+
+			if ok {
+				h.items = append(h.items, mergeItem[T]{val: val, src: i})
+			}
+		}
+
+		heap.Init(h)
+
+		for h.Len() > 0 {
+			item := h.items[0]
+
+			select {
+			case <-ctx.Done():
+				return
+			case dest <- item.val:
+			}
+
+			val, ok, done := recvContext(ctx, sources[item.src])
+			if done {
+				return
+			}
+
+			if ok {
+				h.items[0].val = val
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+		}
+	}()
+
+	return dest
+}
+
+// recvContext receives the value from the channel. It reports whether the value
+// was received (the channel isn't closed) and whether the context is done.
+func recvContext[T any](ctx context.Context, ch <-chan T) (val T, ok, done bool) {
+	select {
+	case <-ctx.Done():
+		return val, false, true
+	case val, ok = <-ch:
+		return val, ok, false
+	}
+}
+
+type mergeItem[T any] struct {
+	val T
+	src int
+}
+
+// mergeHeap implements heap.Interface over the head values of the merged sources.
+type mergeHeap[T any] struct {
+	items []mergeItem[T]
+	less  func(a, b T) bool
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.items) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.val, b.val) {
+		return true
+	}
+
+	if h.less(b.val, a.val) {
+		return false
+	}
+
+	return a.src < b.src
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap[T]) Push(x any) { h.items = append(h.items, x.(mergeItem[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	n := len(h.items)
+	item := h.items[n-1]
+	h.items = h.items[:n-1]
+
+	return item
+}
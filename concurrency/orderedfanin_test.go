@@ -0,0 +1,83 @@
+package concurrency
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func intLess(a, b int) bool {
+	return a < b
+}
+
+func sliceChan[T any](vals []T, delay time.Duration) <-chan T {
+	ch := make(chan T)
+
+	go func() {
+		for _, val := range vals {
+			time.Sleep(delay)
+			ch <- val
+		}
+
+		close(ch)
+	}()
+
+	return ch
+}
+
+func TestOrderedFanIn(t *testing.T) {
+	inputs := [][]int{
+		{1, 4, 7, 10, 13, 16},
+		{2, 5},
+		{},
+		{0, 3, 3, 6, 8, 9, 11, 12, 20},
+	}
+
+	var want []int
+	sources := make([]<-chan int, len(inputs))
+
+	for i, vals := range inputs {
+		want = append(want, vals...)
+		// Different delays make the sources close at different times.
+		sources[i] = sliceChan(vals, time.Duration(i)*time.Millisecond)
+	}
+
+	sort.Ints(want)
+
+	var got []int
+	for val := range OrderedFanIn(context.Background(), intLess, sources...) {
+		got = append(got, val)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong OrderedFanIn result: got %v, want %v", got, want)
+	}
+}
+
+func TestOrderedFanInCancel(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		dest := OrderedFanIn(ctx, intLess, Generator(ctx), Generator(ctx))
+
+		want := []int{0, 0, 1, 1, 2, 2}
+		got := make([]int, len(want))
+
+		for i := range got {
+			got[i] = <-dest
+		}
+
+		cancel()
+
+		for range dest {
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong OrderedFanIn result: got %v, want %v", got, want)
+		}
+	})
+}
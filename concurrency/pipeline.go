@@ -1,5 +1,10 @@
 package concurrency
 
+import (
+	"context"
+	"sync"
+)
+
 // Job is a function type that is used to construct stages in Pipeline.
 type Job func(in <-chan any, out chan<- any)
 
@@ -9,6 +14,7 @@ type Job func(in <-chan any, out chan<- any)
 // two channels, where first one is for sending value to Pipeline, another one is
 // for receiving a result through all the stages of Pipeline. If the sending channel
 // is closed, it'll close other channels between stages, and Pipeline will be finished.
+// A panicking job crashes the process unless it's wrapped by RecoverJob.
 //
 // By using a Pipeline, it separates the concerns of each stage,
 // which provides numerous benefits such as:
@@ -34,3 +40,92 @@ func Pipeline(jobs ...Job) (chan<- any, <-chan any) {
 
 	return resIn, out
 }
+
+// ErrJob is a function type that is used to construct stages in ErrPipeline.
+// Unlike Job, it can fail returning the error. It must stop as soon as the context is done,
+// in particular it mustn't block on sending to the out channel after that.
+type ErrJob func(ctx context.Context, in <-chan any, out chan<- any) error
+
+// ErrPipeline is a Pipeline variant where each stage can fail. It works like errgroup: the first
+// error cancels the context shared by all the stages, so the whole pipeline stops, and the error
+// is returned by Wait.
+type ErrPipeline struct {
+	in     chan any
+	out    <-chan any
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewErrPipeline constructs ErrPipeline and starts its stages. It also returns the context
+// derived from the given one, which is canceled as soon as any stage fails. The sender of
+// the values to the pipeline should watch this context to stop sending when the pipeline fails.
+//
+// The values are forwarded from the input channel to the first stage by a separate goroutine,
+// which stops and closes the first stage's input once the context is done. So even the stages
+// that keep reading their input until it's closed finish after a failure, and Wait returns
+// without the input channel being closed.
+func NewErrPipeline(ctx context.Context, jobs ...ErrJob) (*ErrPipeline, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	in := make(chan any)
+	first := make(chan any)
+	p := &ErrPipeline{in: in, out: first, cancel: cancel}
+
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+		defer close(first)
+
+		for {
+			val, ok, done := recvContext(ctx, in)
+			if !ok || done || !sendContext(ctx, first, val) {
+				return
+			}
+		}
+	}()
+
+	for _, j := range jobs {
+		job := j
+		out := make(chan any)
+
+		p.wg.Add(1)
+
+		go func(in <-chan any, out chan<- any) {
+			defer p.wg.Done()
+			defer close(out)
+
+			if err := job(ctx, in, out); err != nil {
+				p.errOnce.Do(func() {
+					p.err = err
+					p.cancel()
+				})
+			}
+		}(p.out, out)
+
+		p.out = out
+	}
+
+	return p, ctx
+}
+
+// In returns the channel for sending values to the pipeline. Closing it finishes the pipeline.
+func (p *ErrPipeline) In() chan<- any {
+	return p.in
+}
+
+// Out returns the channel for receiving the results. It's closed when the last stage returns.
+func (p *ErrPipeline) Out() <-chan any {
+	return p.out
+}
+
+// Wait blocks until all the stages return and returns the first error, if any.
+func (p *ErrPipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	return p.err
+}
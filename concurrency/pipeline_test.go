@@ -1,6 +1,9 @@
 package concurrency
 
 import (
+	"context"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -39,3 +42,134 @@ func TestPipeline(t *testing.T) {
 		testutils.CheckClosedChan(t, out, testutils.WithDuration(50*time.Millisecond))
 	})
 }
+
+func incrErrJob(ctx context.Context, in <-chan any, out chan<- any) error {
+	for {
+		val, ok, done := recvContext(ctx, in)
+		if done {
+			return ctx.Err()
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if !sendContext(ctx, out, any(val.(int)+1)) {
+			return ctx.Err()
+		}
+	}
+}
+
+// failOnErrJob returns ErrJob that forwards the values until it receives the failing one.
+func failOnErrJob(failing int, err error) ErrJob {
+	return func(ctx context.Context, in <-chan any, out chan<- any) error {
+		for {
+			val, ok, done := recvContext(ctx, in)
+			if done {
+				return ctx.Err()
+			}
+
+			if !ok {
+				return nil
+			}
+
+			if val == failing {
+				return err
+			}
+
+			if !sendContext(ctx, out, val) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func TestErrPipeline(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		p, _ := NewErrPipeline(context.Background(), incrErrJob, incrErrJob)
+
+		testutils.WriteChan(t, p.In(), 0, testutils.WithDuration(50*time.Millisecond))
+		val := testutils.ReadChan(t, p.Out(), testutils.WithDuration(50*time.Millisecond))
+
+		if val != 2 {
+			t.Errorf("wrong pipeline result: got %d, want 2", val)
+		}
+
+		close(p.In())
+		testutils.CheckClosedChan(t, p.Out(), testutils.WithDuration(50*time.Millisecond))
+
+		if err := p.Wait(); err != nil {
+			t.Errorf("received unexpected error: %v", err)
+		}
+	})
+}
+
+func TestErrPipelineFailure(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		errFailed := errors.New("failed")
+		p, ctx := NewErrPipeline(context.Background(), incrErrJob, failOnErrJob(3, errFailed), incrErrJob)
+
+		go func() {
+			defer close(p.In())
+
+			for i := 0; ; i++ {
+				if !sendContext(ctx, p.In(), any(i)) {
+					return
+				}
+			}
+		}()
+
+		var got []any
+		for val := range p.Out() {
+			got = append(got, val)
+		}
+
+		// The values still in flight when the stage fails may be dropped,
+		// but nothing after the failing value gets through.
+		if want := []any{2, 3}; len(got) > len(want) || !reflect.DeepEqual(got, want[:len(got)]) {
+			t.Errorf("wrong pipeline result: got %v, want a prefix of %v", got, want)
+		}
+
+		if err := p.Wait(); !errors.Is(err, errFailed) {
+			t.Errorf("received unexpected error: %v", err)
+		}
+	})
+}
+
+// rangeErrJob reads the input until it's closed, ignoring the context while receiving.
+func rangeErrJob(ctx context.Context, in <-chan any, out chan<- any) error {
+	for val := range in {
+		if !sendContext(ctx, out, val) {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func TestErrPipelineFailureWithoutClosingInput(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		errFailed := errors.New("failed")
+		p, _ := NewErrPipeline(context.Background(), rangeErrJob, failOnErrJob(1, errFailed))
+
+		testutils.WriteChan(t, p.In(), 1, testutils.WithDuration(50*time.Millisecond))
+
+		// The input isn't closed, still the first stage finishes after the failure.
+		waited := make(chan error)
+
+		go func() {
+			waited <- p.Wait()
+		}()
+
+		select {
+		case err := <-waited:
+			if !errors.Is(err, errFailed) {
+				t.Errorf("received unexpected error: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Wait hangs after the failure")
+		}
+
+		testutils.CheckClosedChan(t, p.Out(), testutils.WithDuration(50*time.Millisecond))
+	})
+}
@@ -0,0 +1,796 @@
+package concurrency
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrPoolClosed is returned when the value is submitted to Pool whose input channel is closed
+	// or which is shut down.
+	ErrPoolClosed = errors.New("pool is closed")
+	// ErrPoolStopped is returned when the submitted value is dropped from the queue by Stop.
+	ErrPoolStopped = errors.New("pool is stopped")
+	// ErrWorkerPanicked is returned when WorkerFunc panics on the submitted value
+	// and the panic is recovered by the dead-letter handler.
+	ErrWorkerPanicked = errors.New("worker panicked")
+)
+
+// defaultAutoscaleInterval is the autoscaler's Interval used if it isn't set.
+const defaultAutoscaleInterval = 100 * time.Millisecond
+
+// AutoscaleConfig configures the autoscaler of Pool.
+type AutoscaleConfig struct {
+	// Min and Max bound the number of workers. Min is at least 1, and Max is at least Min.
+	Min, Max int
+	// Interval is how often the autoscaler checks the pool. Default is 100ms.
+	Interval time.Duration
+	// IdleTimeout is how long some of the workers have to stay idle before the pool shrinks by one worker.
+	IdleTimeout time.Duration
+}
+
+type poolConfig struct {
+	deadLetters DeadLetterHandler
+	queueSize   int
+	autoscale   *AutoscaleConfig
+	// orderWindow enables the ordered results if it's positive.
+	orderWindow int
+	// prioritized enables the priority queue, see WithPriorities.
+	prioritized bool
+	aging       time.Duration
+	now         func() time.Time
+	// key enables the per-key serialized execution, see WithKey.
+	key        func(val any) string
+	keyBacklog int
+}
+
+// PoolOption configures Pool and WorkerPool.
+type PoolOption func(cfg *poolConfig)
+
+// WithDeadLetters makes the workers recover the panics of WorkerFunc. The value that has made
+// the function panic is passed to the handler and produces no result, the worker continues
+// with the next values. The handler also gets the values from the input channel that the pool
+// drops without processing, see Pool.Dropped.
+func WithDeadLetters(h DeadLetterHandler) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.deadLetters = h
+	}
+}
+
+// WithQueueSize sets how many values may wait in the queue for a free worker. Default is 1.
+func WithQueueSize(n int) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.queueSize = n
+	}
+}
+
+// WithAutoscale makes Pool adjust the number of workers between the bounds: it grows
+// while the values wait in the queue and shrinks by one worker when some of them stay idle
+// for the timeout.
+func WithAutoscale(cfg AutoscaleConfig) PoolOption {
+	return func(c *poolConfig) {
+		c.autoscale = &cfg
+	}
+}
+
+// WithOrderedResults makes Pool emit the results to the output channel in the order the values
+// are sent to the input channel. The results that are ready out of order wait in the reorder buffer
+// until the preceding ones are emitted. The window bounds the number of values that are queued,
+// processed or buffered at once, so the memory stays capped: when it's reached, the pool stops
+// taking new values until the oldest one is emitted.
+func WithOrderedResults(window int) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.orderWindow = window
+	}
+}
+
+// Pool is the WorkerPool which number of workers can be changed at runtime, either explicitly
+// by Resize or by the autoscaler enabled with WithAutoscale option.
+//
+// The values sent to the input channel are put into the queue, and the workers take them from there.
+// When the pool shrinks, the excess workers exit once they finish the values they are processing.
+// When the input channel is closed, the workers process the values left in the queue and exit,
+// then the output channel is closed.
+//
+// Besides the input channel, the values can be submitted by Submit, which returns Future
+// of the value's result instead of emitting it to the output channel.
+//
+// The pool can also be finished without closing the input channel: Shutdown stops taking new values
+// and lets the workers finish the queued ones, while Stop drops the queued values and cancels
+// the context passed to ContextWorkerFunc.
+type Pool struct {
+	fn  ContextWorkerFunc
+	cfg poolConfig
+	in  chan any
+	out chan any
+	// ctx is passed to the function and canceled by Stop.
+	ctx  context.Context
+	stop context.CancelFunc
+	// closing is closed when the pool is shut down, so the feeder stops queueing the input values.
+	closing   chan struct{}
+	closeOnce sync.Once
+	// results passes the results to the reordering goroutine if the results are ordered.
+	results chan seqValue
+	// done is closed when all the workers exit and the output channel is closed.
+	done chan struct{}
+
+	mu sync.Mutex
+	// notEmpty wakes up the workers waiting for values, notFull wakes up the submitters.
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	queue    taskHeap
+	size     int
+	running  int
+	busy     int
+	closed   bool
+	finished bool
+	// draining is set by Shutdown and Stop, stopped is set by Stop only.
+	draining bool
+	stopped  bool
+	// dropped is the number of the values from the input channel dropped without processing.
+	dropped int64
+	// active holds the Futures of the submitted values that are being processed.
+	active map[*InnerFuture[any]]struct{}
+	// seq is the sequence number of the next value from the input channel,
+	// emitted is the number of the values that have left the reorder buffer.
+	seq     int
+	emitted int
+	// pushed is the number of the values ever put into the queue, start is the moment
+	// the aging of the priorities is counted from.
+	pushed int
+	start  time.Time
+	// keys holds the backlogs of the keys that have a value queued or being processed,
+	// backlogged is the total number of the values in the backlogs.
+	keys       map[string][]poolTask
+	backlogged int
+}
+
+// poolTask is the value waiting in the queue of Pool.
+type poolTask struct {
+	val any
+	seq int
+	// future is set if the value is submitted by Submit.
+	future *InnerFuture[any]
+	ctx    context.Context
+	// rank is the priority of the task with its aging applied, order breaks the ties.
+	rank  int64
+	order int
+	key   string
+}
+
+// NewPool constructs Pool with n workers performing the function.
+func NewPool(n int, fn WorkerFunc, opts ...PoolOption) *Pool {
+	return NewContextPool(n, func(_ context.Context, in any) any {
+		return fn(in)
+	}, opts...)
+}
+
+// NewContextPool constructs Pool with n workers performing the context-aware function.
+// The function gets the context that is canceled by Stop, or the submission's context
+// for the values submitted by Submit.
+func NewContextPool(n int, fn ContextWorkerFunc, opts ...PoolOption) *Pool {
+	cfg := poolConfig{queueSize: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.queueSize < 1 {
+		cfg.queueSize = 1
+	}
+
+	if cfg.keyBacklog < 1 {
+		cfg.keyBacklog = cfg.queueSize
+	}
+
+	if cfg.now == nil {
+		cfg.now = time.Now
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	p := &Pool{
+		fn:      fn,
+		cfg:     cfg,
+		in:      make(chan any),
+		out:     make(chan any),
+		ctx:     ctx,
+		stop:    stop,
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		active:  make(map[*InnerFuture[any]]struct{}),
+		start:   cfg.now(),
+		keys:    make(map[string][]poolTask),
+	}
+
+	p.notEmpty = sync.NewCond(&p.mu)
+	p.notFull = sync.NewCond(&p.mu)
+
+	if cfg.orderWindow > 0 {
+		p.results = make(chan seqValue)
+		go p.reorder()
+	}
+
+	if cfg.autoscale != nil {
+		as := cfg.autoscale.normalize()
+		n = clamp(n, as.Min, as.Max)
+
+		go p.autoscale(as)
+	}
+
+	p.Resize(n)
+
+	go p.feed()
+
+	return p
+}
+
+// In returns the channel for sending values to the workers. Closing it finishes the pool.
+func (p *Pool) In() chan<- any {
+	return p.in
+}
+
+// Out returns the channel for receiving the results.
+func (p *Pool) Out() <-chan any {
+	return p.out
+}
+
+// Size returns the number of workers the pool is resized to.
+func (p *Pool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.size
+}
+
+// Resize changes the number of workers. The new workers start straightaway, while the excess
+// ones exit after finishing the values they are processing. The pool has at least one worker.
+func (p *Pool) Resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resize(n)
+}
+
+func (p *Pool) resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	p.size = n
+
+	for ; p.running < p.size && !p.finished; p.running++ {
+		go p.work()
+	}
+
+	// Wakes up the idle workers, so the excess ones exit.
+	p.notEmpty.Broadcast()
+}
+
+// Shutdown stops taking new values and waits until the workers process the values already
+// in the queue and the output channel is closed. If the context is done first, Shutdown
+// returns the context error, the workers keep processing the remaining values in the background,
+// so Stop may be called to abort them.
+//
+// Submit fails with ErrPoolClosed after Shutdown. The values sent to the input channel while
+// the pool is finishing are dropped and reported, see Dropped. Once the pool is finished,
+// the input channel isn't read anymore, so the producers must stop sending by then, but they
+// don't have to close the channel.
+//
+// The results are still emitted to the output channel, so it has to be read for Shutdown to complete.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.draining = true
+	p.notFull.Broadcast()
+	p.mu.Unlock()
+
+	p.closeOnce.Do(func() {
+		close(p.closing)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-p.done:
+		return nil
+	}
+}
+
+// Stop stops taking new values, drops the queued ones and cancels the context of the values
+// being processed, then waits until the workers exit. The results of the aborted values aren't
+// emitted, the Futures of the dropped submitted values fail with ErrPoolStopped and the ones
+// being processed fail with context.Canceled.
+//
+// The values from the input channel that are dropped are reported, see Dropped.
+//
+// The function that ignores the context delays Stop until it returns.
+func (p *Pool) Stop() {
+	var dropped []any
+
+	discard := func(task poolTask) {
+		if task.future != nil {
+			task.future.complete(nil, ErrPoolStopped)
+		} else {
+			dropped = append(dropped, task.val)
+		}
+	}
+
+	p.mu.Lock()
+	p.draining = true
+	p.stopped = true
+
+	for _, task := range p.queue {
+		discard(task)
+	}
+
+	p.queue = nil
+
+	for key, backlog := range p.keys {
+		for _, task := range backlog {
+			discard(task)
+		}
+
+		p.keys[key] = nil
+	}
+
+	p.backlogged = 0
+
+	for f := range p.active {
+		f.Cancel()
+	}
+
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+	p.mu.Unlock()
+
+	// The handler is called without the lock, so it may be slow or use the pool.
+	for _, val := range dropped {
+		p.drop(val, ErrPoolStopped)
+	}
+
+	p.closeOnce.Do(func() {
+		close(p.closing)
+	})
+	p.stop()
+
+	<-p.done
+}
+
+// Dropped returns the number of the values from the input channel that the pool has dropped
+// without processing: the ones queued when Stop is called and the ones sent after Shutdown
+// or Stop. If the dead-letter handler is set with WithDeadLetters, it gets every dropped value
+// as well.
+func (p *Pool) Dropped() int64 {
+	return atomic.LoadInt64(&p.dropped)
+}
+
+// drop reports the value from the input channel that is dropped for the reason.
+func (p *Pool) drop(val any, reason error) {
+	atomic.AddInt64(&p.dropped, 1)
+
+	if p.cfg.deadLetters != nil {
+		p.cfg.deadLetters(DeadLetter{Input: val, Panic: reason})
+	}
+}
+
+// Submit puts the value into the queue, waiting for room in it if necessary, and returns
+// Future of the value's result. The result isn't emitted to the output channel and doesn't
+// take part in the ordering of WithOrderedResults.
+//
+// Like with Executor, the Future fails as soon as the context is done, even if the value
+// is still in the queue, and such a value isn't processed when a worker takes it.
+func (p *Pool) Submit(ctx context.Context, val any) Future[any] {
+	return p.SubmitPriority(ctx, val, 0)
+}
+
+// SubmitPriority is Submit that puts the value into the queue with the given priority.
+// The priority only matters if the pool is constructed with WithPriorities, otherwise
+// the values are taken from the queue in the order they are put there.
+func (p *Pool) SubmitPriority(ctx context.Context, val any, priority int) Future[any] {
+	promise, ctx := newPromise[any](ctx)
+	f := promise.future
+	key := p.keyOf(val)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.waitRoom(ctx, func() bool { return p.hasRoom(key) }); err != nil {
+		f.complete(nil, err)
+		return f
+	}
+
+	p.push(poolTask{val: val, future: f, ctx: ctx, key: key}, priority)
+
+	return f
+}
+
+// waitRoom waits until the condition is true, the pool is closed or the context is done.
+// It must be called with the lock held.
+func (p *Pool) waitRoom(ctx context.Context, cond func() bool) error {
+	if p.closed || p.draining {
+		return ErrPoolClosed
+	}
+
+	if cond() {
+		return nil
+	}
+
+	if ctx.Done() != nil {
+		// The condition variable can't wait for the context, so the waiting is interrupted
+		// by the goroutine that lives until the room is found.
+		stop := make(chan struct{})
+		defer close(stop)
+
+		go func() {
+			select {
+			case <-stop:
+			case <-ctx.Done():
+				p.mu.Lock()
+				p.notFull.Broadcast()
+				p.mu.Unlock()
+			}
+		}()
+	}
+
+	for !cond() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if p.closed || p.draining {
+			return ErrPoolClosed
+		}
+
+		p.notFull.Wait()
+	}
+
+	return nil
+}
+
+// hasRoom reports whether there is room for the value with the key. If the values are keyed and
+// the key is busy, the value goes to the key's backlog, otherwise it goes to the queue.
+func (p *Pool) hasRoom(key string) bool {
+	if p.cfg.key != nil {
+		if backlog, busy := p.keys[key]; busy {
+			return len(backlog) < p.cfg.keyBacklog
+		}
+	}
+
+	return len(p.queue) < p.cfg.queueSize
+}
+
+// hasOrderedRoom reports whether there is room both for the value and in the reorder window.
+func (p *Pool) hasOrderedRoom(key string) bool {
+	return p.hasRoom(key) && (p.results == nil || p.seq-p.emitted < p.cfg.orderWindow)
+}
+
+// push puts the task into the queue, the values from the input channel have zero priority.
+func (p *Pool) push(task poolTask, priority int) {
+	task.order = p.pushed
+	p.pushed++
+
+	if p.cfg.key != nil && p.backlog(&task) {
+		return
+	}
+
+	if p.cfg.prioritized {
+		task.rank = p.rank(priority)
+		heap.Push(&p.queue, task)
+	} else {
+		p.queue = append(p.queue, task)
+	}
+
+	p.notEmpty.Signal()
+}
+
+// pop takes the next task from the non-empty queue.
+func (p *Pool) pop() poolTask {
+	if p.cfg.prioritized {
+		return heap.Pop(&p.queue).(poolTask)
+	}
+
+	task := p.queue[0]
+	p.queue[0] = poolTask{}
+	p.queue = p.queue[1:]
+
+	return task
+}
+
+// feed moves the values from the input channel to the queue until the channel is closed
+// or the pool is shut down. After the shutdown, it drops the values sent to the input channel
+// until the pool is finished, so the senders don't block while the pool is finishing.
+func (p *Pool) feed() {
+	for p.receive() {
+	}
+
+	p.mu.Lock()
+	p.closed = true
+	p.notEmpty.Broadcast()
+	p.notFull.Broadcast()
+	reason := ErrPoolClosed
+	if p.stopped {
+		reason = ErrPoolStopped
+	}
+	p.mu.Unlock()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case val, ok := <-p.in:
+			if !ok {
+				return
+			}
+
+			p.drop(val, reason)
+		}
+	}
+}
+
+// receive moves one value from the input channel to the queue. It reports whether the feeder
+// has to continue.
+func (p *Pool) receive() bool {
+	// Checks the shutdown first, as select picks a random case if the input is ready too.
+	select {
+	case <-p.closing:
+		return false
+	default:
+	}
+
+	var val any
+
+	select {
+	case <-p.closing:
+		return false
+	case v, ok := <-p.in:
+		if !ok {
+			return false
+		}
+
+		val = v
+	}
+
+	key := p.keyOf(val)
+
+	p.mu.Lock()
+
+	for !p.hasOrderedRoom(key) && !p.stopped {
+		p.notFull.Wait()
+	}
+
+	if p.stopped {
+		p.mu.Unlock()
+		p.drop(val, ErrPoolStopped)
+
+		return false
+	}
+
+	p.push(poolTask{val: val, seq: p.seq, key: key}, 0)
+	p.seq++
+	p.mu.Unlock()
+
+	return true
+}
+
+func (p *Pool) work() {
+	for {
+		task, ok := p.next()
+		if !ok {
+			return
+		}
+
+		for ok {
+			p.process(task)
+			task, ok = p.release(task)
+		}
+	}
+}
+
+// release marks the task as processed. If the values are keyed, it returns the next value
+// of the same key, so the worker processes it straightaway.
+func (p *Pool) release(task poolTask) (poolTask, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cfg.key != nil {
+		if next, ok := p.nextOfKey(task.key); ok {
+			return next, true
+		}
+	}
+
+	p.busy--
+
+	return poolTask{}, false
+}
+
+// process performs the function for the task and delivers the result.
+func (p *Pool) process(task poolTask) {
+	if task.future != nil {
+		if err := task.ctx.Err(); err != nil {
+			p.deactivate(task.future)
+			task.future.complete(nil, err)
+
+			return
+		}
+
+		res, ok := p.call(task.ctx, task.val)
+		p.deactivate(task.future)
+
+		if !ok {
+			task.future.complete(nil, ErrWorkerPanicked)
+			return
+		}
+
+		task.future.complete(res, nil)
+
+		return
+	}
+
+	res, ok := p.call(p.ctx, task.val)
+
+	switch {
+	case p.results != nil:
+		p.results <- seqValue{seq: task.seq, val: res, skip: !ok || p.ctx.Err() != nil}
+	case ok:
+		p.emit(res)
+	}
+}
+
+// deactivate forgets the Future of the submitted value before it's completed.
+func (p *Pool) deactivate(f *InnerFuture[any]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.active, f)
+}
+
+func (p *Pool) call(ctx context.Context, val any) (any, bool) {
+	return safeCall(func(in any) any {
+		return p.fn(ctx, in)
+	}, val, p.cfg.deadLetters)
+}
+
+// emit sends the result to the output channel unless the pool is stopped.
+func (p *Pool) emit(res any) {
+	select {
+	case p.out <- res:
+	case <-p.ctx.Done():
+	}
+}
+
+// next takes the next task from the queue, blocking until there is one.
+// It returns false if the worker has to exit.
+func (p *Pool) next() (poolTask, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		switch {
+		case p.running > p.size:
+			p.running--
+			return poolTask{}, false
+		case len(p.queue) > 0:
+			task := p.pop()
+			p.busy++
+			p.take(task)
+
+			return task, true
+		case p.closed:
+			p.running--
+			if p.running == 0 {
+				p.finish()
+			}
+
+			return poolTask{}, false
+		}
+
+		p.notEmpty.Wait()
+	}
+}
+
+// take notes that the task is taken from the queue or the backlog by a worker.
+func (p *Pool) take(task poolTask) {
+	p.notFull.Broadcast()
+
+	if task.future != nil {
+		p.active[task.future] = struct{}{}
+	}
+}
+
+// finish closes the output channel once the last worker exits.
+func (p *Pool) finish() {
+	p.finished = true
+
+	if p.results != nil {
+		// The reordering goroutine closes the output after emitting the rest of the results.
+		close(p.results)
+		return
+	}
+
+	close(p.out)
+	close(p.done)
+}
+
+// reorder emits the results in the order of the values if the results are ordered.
+func (p *Pool) reorder() {
+	reorder(p.results, p.emit, func() {
+		p.mu.Lock()
+		p.emitted++
+		p.notFull.Broadcast()
+		p.mu.Unlock()
+	})
+
+	close(p.out)
+	close(p.done)
+}
+
+// normalize fixes the config the autoscaler can't work with.
+func (c AutoscaleConfig) normalize() AutoscaleConfig {
+	if c.Min < 1 {
+		c.Min = 1
+	}
+
+	if c.Max < c.Min {
+		c.Max = c.Min
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = defaultAutoscaleInterval
+	}
+
+	if c.IdleTimeout < 0 {
+		c.IdleTimeout = 0
+	}
+
+	return c
+}
+
+// autoscale periodically resizes the pool according to the queue depth and the idle workers.
+func (p *Pool) autoscale(cfg AutoscaleConfig) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	var idleSince time.Time
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+
+			// The backlogged values wait for a worker too, just not for any of the idle ones.
+			waiting := len(p.queue) + p.backlogged
+
+			switch {
+			case waiting > 0:
+				idleSince = time.Time{}
+				p.resize(clamp(p.size+waiting, cfg.Min, cfg.Max))
+			case p.busy >= p.size:
+				idleSince = time.Time{}
+			case idleSince.IsZero():
+				idleSince = now
+			case now.Sub(idleSince) >= cfg.IdleTimeout:
+				idleSince = now
+				p.resize(clamp(p.size-1, cfg.Min, cfg.Max))
+			}
+
+			p.mu.Unlock()
+		}
+	}
+}
+
+func clamp(n, lo, hi int) int {
+	if n < lo {
+		return lo
+	}
+
+	if n > hi {
+		return hi
+	}
+
+	return n
+}
@@ -0,0 +1,506 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+// concurrencyMeter counts how many calls of the function run at the same time.
+type concurrencyMeter struct {
+	running int64
+	max     int64
+}
+
+func (m *concurrencyMeter) wrap(fn WorkerFunc) WorkerFunc {
+	return func(in any) any {
+		n := atomic.AddInt64(&m.running, 1)
+		defer atomic.AddInt64(&m.running, -1)
+
+		for {
+			max := atomic.LoadInt64(&m.max)
+			if n <= max || atomic.CompareAndSwapInt64(&m.max, max, n) {
+				break
+			}
+		}
+
+		return fn(in)
+	}
+}
+
+func (m *concurrencyMeter) reset() int64 {
+	return atomic.SwapInt64(&m.max, atomic.LoadInt64(&m.running))
+}
+
+// waitFor polls the condition until it's true or the timeout is exceeded.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(timeout); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout exceeded while waiting for the condition")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// drain reads the channel until it's closed, the returned channel is closed after that.
+func drain(ch <-chan any) <-chan struct{} {
+	drained := make(chan struct{})
+
+	go func() {
+		for range ch {
+		}
+
+		close(drained)
+	}()
+
+	return drained
+}
+
+func sleepFor(d time.Duration) WorkerFunc {
+	return func(in any) any {
+		time.Sleep(d)
+		return in
+	}
+}
+
+func TestPoolResize(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		var meter concurrencyMeter
+
+		p := NewPool(1, meter.wrap(sleepFor(20*time.Millisecond)), WithQueueSize(100))
+		drained := drain(p.Out())
+
+		send := func(n int) {
+			for i := 0; i < n; i++ {
+				p.In() <- i
+			}
+		}
+
+		send(4)
+		waitFor(t, time.Second, func() bool { return atomic.LoadInt64(&meter.running) == 1 })
+
+		p.Resize(4)
+		if p.Size() != 4 {
+			t.Errorf("wrong pool size: got %d, want 4", p.Size())
+		}
+
+		send(8)
+		waitFor(t, time.Second, func() bool { return atomic.LoadInt64(&meter.max) == 4 })
+
+		// The in-flight values of the excess workers are finished, not dropped.
+		p.Resize(1)
+		meter.reset()
+		send(8)
+		waitFor(t, time.Second, func() bool { return atomic.LoadInt64(&meter.running) <= 1 })
+		meter.reset()
+		send(4)
+		time.Sleep(50 * time.Millisecond)
+
+		if max := meter.reset(); max > 1 {
+			t.Errorf("pool isn't shrunk: %d workers run concurrently", max)
+		}
+
+		close(p.In())
+		<-drained
+	})
+}
+
+func TestPoolResizeKeepsResults(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		const n = 50
+
+		p := NewPool(8, sleepFor(time.Millisecond))
+
+		go func() {
+			for i := 0; i < n; i++ {
+				p.In() <- i
+
+				if i%10 == 0 {
+					p.Resize(1 + i%7)
+				}
+			}
+
+			close(p.In())
+		}()
+
+		var got int
+		for range p.Out() {
+			got++
+		}
+
+		if got != n {
+			t.Errorf("wrong number of results: got %d, want %d", got, n)
+		}
+	})
+}
+
+func TestPoolAutoscale(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		release := make(chan struct{})
+
+		p := NewPool(1, func(in any) any {
+			<-release
+			return in
+		}, WithQueueSize(10), WithAutoscale(AutoscaleConfig{
+			Min:         1,
+			Max:         4,
+			Interval:    5 * time.Millisecond,
+			IdleTimeout: 20 * time.Millisecond,
+		}))
+
+		drained := drain(p.Out())
+
+		// The values wait in the queue, so the pool grows up to the max.
+		for i := 0; i < 10; i++ {
+			p.In() <- i
+		}
+
+		waitFor(t, time.Second, func() bool { return p.Size() == 4 })
+
+		// The workers become idle, so the pool shrinks down to the min.
+		close(release)
+		waitFor(t, time.Second, func() bool { return p.Size() == 1 })
+
+		close(p.In())
+		<-drained
+	})
+}
+
+func TestPoolSubmit(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		p := NewPool(4, func(in any) any {
+			if in == 3 {
+				panic("three is unlucky")
+			}
+
+			return sleepyIncr(in)
+		}, WithDeadLetters(func(DeadLetter) {}))
+
+		ctx := context.Background()
+
+		futures := make([]Future[any], 10)
+		for i := range futures {
+			futures[i] = p.Submit(ctx, i)
+		}
+
+		// Each Future gets the result of its own value no matter which finishes first.
+		for i, f := range futures {
+			res, err := f.Result()
+
+			if i == 3 {
+				if !errors.Is(err, ErrWorkerPanicked) {
+					t.Errorf("wrong error of the panicking value: got %v, want %v", err, ErrWorkerPanicked)
+				}
+
+				continue
+			}
+
+			if err != nil || res != i+1 {
+				t.Errorf("wrong result of %d: got (%v, %v), want (%d, nil)", i, res, err, i+1)
+			}
+		}
+
+		close(p.In())
+
+		for res := range p.Out() {
+			t.Errorf("submitted result is emitted to the output: %v", res)
+		}
+
+		if _, err := p.Submit(ctx, 1).Result(); !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("wrong error of submitting to the closed pool: got %v, want %v", err, ErrPoolClosed)
+		}
+	})
+}
+
+func TestPoolSubmitCanceled(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		release := make(chan struct{})
+
+		var calls int64
+
+		p := NewPool(1, func(in any) any {
+			atomic.AddInt64(&calls, 1)
+			<-release
+			return in
+		})
+
+		busy := p.Submit(context.Background(), 0)
+		waitFor(t, time.Second, func() bool { return atomic.LoadInt64(&calls) == 1 })
+
+		// The first value fills up the queue, the second one waits for room until it's canceled.
+		ctx, cancel := context.WithCancel(context.Background())
+		queued := p.Submit(ctx, 1)
+		blocked := make(chan Future[any])
+
+		go func() {
+			blocked <- p.Submit(ctx, 2)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		if _, err := (<-blocked).Result(); !errors.Is(err, context.Canceled) {
+			t.Errorf("wrong error of the blocked submission: got %v, want %v", err, context.Canceled)
+		}
+
+		close(release)
+
+		if _, err := queued.Result(); !errors.Is(err, context.Canceled) {
+			t.Errorf("wrong error of the queued submission: got %v, want %v", err, context.Canceled)
+		}
+
+		if res, err := busy.Result(); err != nil || res != 0 {
+			t.Errorf("wrong result of the busy submission: got (%v, %v), want (0, nil)", res, err)
+		}
+
+		close(p.In())
+		<-drain(p.Out())
+
+		if n := atomic.LoadInt64(&calls); n != 1 {
+			t.Errorf("canceled values are processed: got %d calls, want 1", n)
+		}
+	})
+}
+
+func TestPoolSubmitCanceledForgotten(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		release := make(chan struct{})
+
+		p := NewPool(1, func(in any) any {
+			<-release
+			return in
+		}, WithQueueSize(5))
+
+		busy := p.Submit(context.Background(), 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		for i := 1; i <= 5; i++ {
+			p.Submit(ctx, i)
+		}
+
+		cancel()
+		close(release)
+
+		if _, err := busy.Result(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		close(p.In())
+		<-drain(p.Out())
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if n := len(p.active); n != 0 {
+			t.Errorf("canceled submissions are left in the active set: got %d, want 0", n)
+		}
+	})
+}
+
+func TestPoolOrderedResults(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		const window = 4
+
+		vals := make([]int, 40)
+		want := make([]int, 0, len(vals))
+
+		for i := range vals {
+			vals[i] = i
+			if i != 3 {
+				want = append(want, i+1)
+			}
+		}
+
+		var inFlight, maxInFlight int64
+
+		p := NewPool(8, func(in any) any {
+			n := atomic.AddInt64(&inFlight, 1)
+			for {
+				max := atomic.LoadInt64(&maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+					break
+				}
+			}
+
+			defer atomic.AddInt64(&inFlight, -1)
+
+			return sleepyIncr(panicOnThree(in))
+		}, WithOrderedResults(window), WithDeadLetters(func(DeadLetter) {}))
+
+		got := runPipeline(p.In(), p.Out(), vals)
+
+		// The panicking value is skipped without stalling the values after it.
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong results: got %v, want %v", got, want)
+		}
+
+		if max := atomic.LoadInt64(&maxInFlight); max > window {
+			t.Errorf("window is exceeded: %d values are processed concurrently, want at most %d", max, window)
+		}
+	})
+}
+
+func TestPoolShutdown(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		release := make(chan struct{})
+		dead := make(chan DeadLetter, 1)
+
+		p := NewPool(1, func(in any) any {
+			<-release
+			return in
+		}, WithQueueSize(4), WithDeadLetters(DeadLetterChan(dead)))
+
+		for i := 0; i < 5; i++ {
+			p.In() <- i
+		}
+
+		results := make(chan []int)
+
+		go func() {
+			var res []int
+			for val := range p.Out() {
+				res = append(res, val.(int))
+			}
+
+			results <- res
+		}()
+
+		// The worker is blocked, so Shutdown gives up on the deadline.
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("wrong error of the expired shutdown: got %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		if _, err := p.Submit(context.Background(), 5).Result(); !errors.Is(err, ErrPoolClosed) {
+			t.Errorf("wrong error of submitting to the shut down pool: got %v, want %v", err, ErrPoolClosed)
+		}
+
+		// The value sent after shutdown is dropped without blocking the sender.
+		select {
+		case p.In() <- 5:
+		case <-time.After(time.Second):
+			t.Error("sending after shutdown blocks")
+		}
+
+		select {
+		case dl := <-dead:
+			if dl.Input != 5 || dl.Panic != ErrPoolClosed {
+				t.Errorf("wrong dead letter of the dropped value: got %v, %v", dl.Input, dl.Panic)
+			}
+		case <-time.After(time.Second):
+			t.Error("dropped value isn't reported")
+		}
+
+		if n := p.Dropped(); n != 1 {
+			t.Errorf("wrong number of dropped values: got %d, want 1", n)
+		}
+
+		// The queued values are drained once the worker is released.
+		close(release)
+
+		if err := p.Shutdown(context.Background()); err != nil {
+			t.Errorf("unexpected shutdown error: %v", err)
+		}
+
+		if got, want := <-results, []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong results: got %v, want %v", got, want)
+		}
+
+		// The input channel isn't closed, but the pool releases its goroutines anyway.
+	})
+}
+
+func TestPoolStop(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		var started, aborted int64
+
+		p := NewContextPool(2, func(ctx context.Context, in any) any {
+			atomic.AddInt64(&started, 1)
+			<-ctx.Done()
+			atomic.AddInt64(&aborted, 1)
+
+			return in
+		}, WithQueueSize(4))
+
+		p.In() <- 0
+		inFlight := p.Submit(context.Background(), 1)
+		queued := p.Submit(context.Background(), 2)
+
+		waitFor(t, time.Second, func() bool { return atomic.LoadInt64(&started) == 2 })
+
+		// The value from the input channel is queued or still held by the feeder,
+		// it's dropped either way.
+		p.In() <- 3
+
+		stopped := make(chan struct{})
+
+		go func() {
+			p.Stop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("Stop doesn't return")
+		}
+
+		if n := atomic.LoadInt64(&aborted); n != 2 {
+			t.Errorf("wrong number of aborted values: got %d, want 2", n)
+		}
+
+		if _, err := inFlight.Result(); !errors.Is(err, context.Canceled) {
+			t.Errorf("wrong error of the in-flight submission: got %v, want %v", err, context.Canceled)
+		}
+
+		if _, err := queued.Result(); !errors.Is(err, ErrPoolStopped) {
+			t.Errorf("wrong error of the queued submission: got %v, want %v", err, ErrPoolStopped)
+		}
+
+		for res := range p.Out() {
+			t.Errorf("result of the aborted value is emitted: %v", res)
+		}
+
+		waitFor(t, time.Second, func() bool { return p.Dropped() == 1 })
+	})
+}
+
+func TestPoolAutoscaleConfig(t *testing.T) {
+	testCases := []struct {
+		name string
+		n    int
+		cfg  AutoscaleConfig
+		want int
+	}{
+		{name: "no interval", n: 2, cfg: AutoscaleConfig{Min: 1, Max: 4}, want: 2},
+		{name: "zero min", n: 0, cfg: AutoscaleConfig{Min: 0, Max: 4, Interval: time.Millisecond}, want: 1},
+		{name: "min above max", n: 1, cfg: AutoscaleConfig{Min: 3, Max: 2, Interval: -time.Second}, want: 3},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+				p := NewPool(tc.n, sleepFor(0), WithAutoscale(tc.cfg))
+
+				if got := p.Size(); got != tc.want {
+					t.Errorf("wrong pool size: got %d, want %d", got, tc.want)
+				}
+
+				close(p.In())
+				<-drain(p.Out())
+			})
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// maxWeightedPriority bounds priority*aging, so subtracting the waiting time from it can't overflow.
+const maxWeightedPriority = math.MaxInt64 / 2
+
+// WithPriorities makes Pool take the values from the queue by their priority instead of the order
+// they are put there: the value with the higher priority is dispatched first, and the values
+// with the same priority are dispatched in the FIFO order. The priority is set by SubmitPriority,
+// the values sent to the input channel have zero priority.
+//
+// A steady flow of the high-priority values may starve the low-priority ones forever, so the aging
+// raises the priority of the waiting value by one for every aging interval it spends in the queue.
+// For instance, with the aging of one second, the value with priority 0 that has waited for
+// 3 seconds is dispatched before the value with priority 2 that has just been submitted.
+// Zero aging disables it.
+//
+// With the aging, priority multiplied by the aging interval in nanoseconds is capped at ±2^62,
+// the priorities beyond that are treated as equal. For instance, with the aging of one second,
+// all the priorities above about 4.6 billion are the same.
+func WithPriorities(aging time.Duration) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.prioritized = true
+		cfg.aging = aging
+	}
+}
+
+// withClock replaces the clock the aging is measured by.
+func withClock(now func() time.Time) PoolOption {
+	return func(cfg *poolConfig) {
+		cfg.now = now
+	}
+}
+
+// rank computes the rank of the value with the given priority submitted now.
+//
+// The effective priority of the value grows with the time it waits: priority + (now - enqueued) / aging.
+// The current time is the same for all the queued values, so they compare the same way
+// as priority*aging - enqueued, which doesn't change over time and can be used as the heap key.
+func (p *Pool) rank(priority int) int64 {
+	if p.cfg.aging <= 0 {
+		return int64(priority)
+	}
+
+	aging := int64(p.cfg.aging)
+	weighted := int64(priority)
+
+	switch {
+	case weighted > maxWeightedPriority/aging:
+		weighted = maxWeightedPriority
+	case weighted < -maxWeightedPriority/aging:
+		weighted = -maxWeightedPriority
+	default:
+		weighted *= aging
+	}
+
+	return weighted - int64(p.cfg.now().Sub(p.start))
+}
+
+// taskHeap is the queue of Pool, which is kept as the max-heap by rank if the priorities are enabled.
+type taskHeap []poolTask
+
+func (h taskHeap) Len() int {
+	return len(h)
+}
+
+func (h taskHeap) Less(i, j int) bool {
+	if h[i].rank != h[j].rank {
+		return h[i].rank > h[j].rank
+	}
+
+	return h[i].order < h[j].order
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *taskHeap) Push(x any) {
+	*h = append(*h, x.(poolTask))
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = poolTask{}
+	*h = old[:n-1]
+
+	return task
+}
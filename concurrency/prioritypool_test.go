@@ -0,0 +1,189 @@
+package concurrency
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+// fakeClock is the clock that moves only when it's advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// prioritySubmit is the value submitted with the priority after advancing the clock.
+type prioritySubmit struct {
+	val      string
+	priority int
+	after    time.Duration
+}
+
+// dispatchOrder submits the values to the pool with the single busy worker,
+// then releases the worker and returns the order the values are processed in.
+func dispatchOrder(t *testing.T, aging time.Duration, submits []prioritySubmit) []string {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		order []string
+		clock fakeClock
+	)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	p := NewPool(1, func(in any) any {
+		if in == "busy" {
+			close(started)
+			<-release
+
+			return in
+		}
+
+		mu.Lock()
+		order = append(order, in.(string))
+		mu.Unlock()
+
+		return in
+	}, WithQueueSize(len(submits)), WithPriorities(aging), withClock(clock.Now))
+
+	ctx := context.Background()
+
+	busy := p.Submit(ctx, "busy")
+	<-started
+
+	futures := make([]Future[any], 0, len(submits))
+
+	for _, s := range submits {
+		clock.Advance(s.after)
+		futures = append(futures, p.SubmitPriority(ctx, s.val, s.priority))
+	}
+
+	close(release)
+
+	if _, err := busy.Result(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, f := range futures {
+		if _, err := f.Result(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	close(p.In())
+	<-drain(p.Out())
+
+	return order
+}
+
+func TestPoolPriorities(t *testing.T) {
+	testCases := []struct {
+		name    string
+		aging   time.Duration
+		submits []prioritySubmit
+		want    []string
+	}{
+		{
+			name:  "higher priority first",
+			aging: 0,
+			submits: []prioritySubmit{
+				{val: "low", priority: 1},
+				{val: "urgent-1", priority: 5},
+				{val: "normal", priority: 3},
+				{val: "urgent-2", priority: 5},
+				{val: "bulk", priority: 0},
+			},
+			want: []string{"urgent-1", "urgent-2", "normal", "low", "bulk"},
+		},
+		{
+			name:  "without aging long wait doesn't matter",
+			aging: 0,
+			submits: []prioritySubmit{
+				{val: "bulk", priority: 0},
+				{val: "urgent", priority: 1, after: time.Hour},
+			},
+			want: []string{"urgent", "bulk"},
+		},
+		{
+			name:  "aging lifts waiting values",
+			aging: 10 * time.Millisecond,
+			submits: []prioritySubmit{
+				// Waits for 50ms, so its effective priority is 5 by the time the worker is released.
+				{val: "bulk", priority: 0},
+				{val: "normal", priority: 3, after: 50 * time.Millisecond},
+				{val: "urgent", priority: 6},
+				{val: "equal", priority: 5},
+			},
+			want: []string{"urgent", "bulk", "equal", "normal"},
+		},
+		{
+			name:  "huge priorities don't overflow",
+			aging: time.Second,
+			submits: []prioritySubmit{
+				{val: "normal", priority: 1},
+				{val: "lowest", priority: math.MinInt},
+				{val: "highest", priority: math.MaxInt},
+				{val: "also highest", priority: math.MaxInt / 2},
+			},
+			want: []string{"highest", "also highest", "normal", "lowest"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			testutils.DetectGoroutineLeeks(t, func() {
+				if got := dispatchOrder(t, tc.aging, tc.submits); !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("wrong dispatch order: got %v, want %v", got, tc.want)
+				}
+			})
+		})
+	}
+}
+
+func TestPoolPrioritiesStarvation(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		submits := []prioritySubmit{{val: "bulk", priority: 0}}
+
+		// The urgent values keep coming every 10ms, but their lead over the bulk value shrinks
+		// by one every 10ms too, so only the first two of them overtake it.
+		for i := 0; i < 20; i++ {
+			submits = append(submits, prioritySubmit{val: "urgent", priority: 3, after: 10 * time.Millisecond})
+		}
+
+		order := dispatchOrder(t, 10*time.Millisecond, submits)
+
+		pos := -1
+		for i, val := range order {
+			if val == "bulk" {
+				pos = i
+			}
+		}
+
+		if pos != 2 {
+			t.Errorf("bulk value is starved: dispatch order %v", order)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNilRejection is the error of the Future whose Promise is rejected with the nil error.
+var ErrNilRejection = errors.New("promise is rejected with nil error")
+
+// Promise is the writing side of Future. While Future is created by Async wrapping a function,
+// Promise allows to complete the linked Future explicitly, for example from a callback
+// that receives the value asynchronously.
+//
+// Only the first completion takes effect: it's the first of Resolve, Reject, Future's Cancel
+// or the context being done. The later completions are no-ops, Resolve and Reject report
+// whether they have completed the Future, so the caller can find out that it lost the race.
+type Promise[T any] struct {
+	future *InnerFuture[T]
+}
+
+// NewPromise constructs Promise. If the context is done before the Promise is completed,
+// the linked Future fails with the context error.
+func NewPromise[T any](ctx context.Context) *Promise[T] {
+	p, _ := newPromise[T](ctx)
+	return p
+}
+
+// newPromise constructs Promise and returns the context that is canceled when
+// the Promise is completed. The goroutine watching the context exits at the same time.
+// If the parent context can never be done, only completing the Promise cancels the context,
+// so there is nothing to watch, and the goroutine isn't started.
+func newPromise[T any](parent context.Context) (*Promise[T], context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+	p := &Promise[T]{future: newInnerFuture[T](cancel)}
+
+	if parent.Done() == nil {
+		return p, ctx
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		var zero T
+		p.future.complete(zero, ctx.Err())
+	}()
+
+	return p, ctx
+}
+
+// Future returns Future linked to the Promise.
+func (p *Promise[T]) Future() Future[T] {
+	return p.future
+}
+
+// Resolve completes the linked Future with the value.
+// It reports whether the Future is completed by this call.
+func (p *Promise[T]) Resolve(val T) bool {
+	return p.future.complete(val, nil)
+}
+
+// Reject completes the linked Future with the error.
+// It reports whether the Future is completed by this call.
+// Rejecting with the nil error is a misuse, it would make the Future look successful,
+// so the Future fails with ErrNilRejection instead.
+func (p *Promise[T]) Reject(err error) bool {
+	if err == nil {
+		err = ErrNilRejection
+	}
+
+	var zero T
+	return p.future.complete(zero, err)
+}
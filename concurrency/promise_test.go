@@ -0,0 +1,130 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func TestPromise(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		p := NewPromise[string](context.Background())
+
+		// Imitates the response handler called on another goroutine.
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			p.Resolve("response")
+		}()
+
+		res, err := p.Future().Result()
+		if err != nil || res != "response" {
+			t.Errorf("wrong result: got (%q, %v), want (\"response\", nil)", res, err)
+		}
+
+		if p.Resolve("another") || p.Reject(errors.New("late")) {
+			t.Error("second completion must be a no-op")
+		}
+
+		if res, _ = p.Future().Result(); res != "response" {
+			t.Errorf("result is changed by the second completion: %q", res)
+		}
+	})
+}
+
+func TestPromiseReject(t *testing.T) {
+	errFailed := errors.New("failed")
+	p := NewPromise[int](context.Background())
+
+	if !p.Reject(errFailed) {
+		t.Error("first completion must succeed")
+	}
+
+	if _, err := p.Future().Result(); !errors.Is(err, errFailed) {
+		t.Errorf("received unexpected error: %v", err)
+	}
+}
+
+func TestPromiseRejectNil(t *testing.T) {
+	p := NewPromise[int](context.Background())
+
+	if !p.Reject(nil) {
+		t.Error("first completion must succeed")
+	}
+
+	if _, err := p.Future().Result(); !errors.Is(err, ErrNilRejection) {
+		t.Errorf("wrong error: got %v, want %v", err, ErrNilRejection)
+	}
+}
+
+func TestPromiseContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewPromise[int](ctx)
+
+	cancel()
+
+	if _, err := p.Future().Result(); !errors.Is(err, context.Canceled) {
+		t.Errorf("received unexpected error: %v", err)
+	}
+
+	if p.Resolve(1) {
+		t.Error("Resolve must lose to the done context")
+	}
+}
+
+// TestPromiseRace makes sure that exactly one of the racing completions wins,
+// and the Future's result is the winner's one.
+func TestPromiseRace(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewPromise[int](ctx)
+
+		var (
+			wg     sync.WaitGroup
+			wins   int64
+			winner int64 = -1
+		)
+
+		for j := 0; j < 10; j++ {
+			wg.Add(1)
+
+			go func(j int) {
+				defer wg.Done()
+
+				var won bool
+				if j%2 == 0 {
+					won = p.Resolve(j)
+				} else {
+					won = p.Reject(errors.New("rejected"))
+				}
+
+				if won {
+					atomic.AddInt64(&wins, 1)
+					atomic.StoreInt64(&winner, int64(j))
+				}
+			}(j)
+		}
+
+		go cancel()
+		wg.Wait()
+
+		res, err := p.Future().Result()
+
+		switch {
+		case wins > 1:
+			t.Fatalf("%d completions won", wins)
+		case wins == 0 && !errors.Is(err, context.Canceled):
+			t.Fatalf("context won, but got error %v", err)
+		case wins == 1 && winner%2 == 0 && (err != nil || int64(res) != winner):
+			t.Fatalf("Resolve(%d) won, but got (%d, %v)", winner, res, err)
+		case wins == 1 && winner%2 == 1 && err == nil:
+			t.Fatalf("Reject won, but got no error")
+		}
+
+		cancel()
+	}
+}
@@ -1,14 +1,38 @@
 package concurrency
 
 import (
-	"crypto/sha1"
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
 	"sync"
+	"unsafe"
 )
 
+// Hasher computes the hash of the key, which determines the shard the key belongs to.
+// The equal keys must have the same hash.
+type Hasher[K comparable] func(key K) uint64
+
+type shardedMapConfig[K comparable] struct {
+	hasher Hasher[K]
+}
+
+// ShardedMapOption configures ShardedMap.
+type ShardedMapOption[K comparable] func(cfg *shardedMapConfig[K])
+
+// WithHasher sets the hash function of the keys. By default, the keys are hashed with hash/maphash:
+// the keys based on strings, integers, floats and pointers are hashed fast, while the composite keys
+// like structs are walked with reflect, which is slower, so it may be better to provide Hasher for them.
+func WithHasher[K comparable](h Hasher[K]) ShardedMapOption[K] {
+	return func(cfg *shardedMapConfig[K]) {
+		cfg.hasher = h
+	}
+}
+
 // Shard is an individually lockable collection representing a single data partition.
-type Shard struct {
+type Shard[K comparable, V any] struct {
 	mu sync.RWMutex
-	m  map[string]any
+	m  map[K]V
 }
 
 // ShardedMap implements vertical-sharding pattern. It splits a large data structure into
@@ -19,23 +43,43 @@ type Shard struct {
 // a hash value is calculated for the key and taking into account the number of shards determines
 // the corresponding shard index. This allows to isolate the necessary locking to only the shard
 // at that index.
-type ShardedMap []*Shard
+type ShardedMap[K comparable, V any] struct {
+	shards []*Shard[K, V]
+	hasher Hasher[K]
+}
+
+// NewShardedMap constructs ShardedMap with string keys and values of any type. It takes the number
+// of shards among which the keys will be distributed.
+func NewShardedMap(n int) *ShardedMap[string, any] {
+	return NewShardedMapOf[string, any](n)
+}
 
-// NewShardedMap constructs ShardedMap. It takes the number of shards among which
-// the keys will be distributed.
-func NewShardedMap(n int) ShardedMap {
-	shards := make([]*Shard, n)
+// NewShardedMapOf is the generic version of NewShardedMap.
+func NewShardedMapOf[K comparable, V any](n int, opts ...ShardedMapOption[K]) *ShardedMap[K, V] {
+	cfg := shardedMapConfig[K]{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.hasher == nil {
+		cfg.hasher = defaultHasher[K]()
+	}
+
+	shards := make([]*Shard[K, V], n)
 	for i := range shards {
-		shards[i] = &Shard{
-			m: make(map[string]any),
+		shards[i] = &Shard[K, V]{
+			m: make(map[K]V),
 		}
 	}
 
-	return shards
+	return &ShardedMap[K, V]{
+		shards: shards,
+		hasher: cfg.hasher,
+	}
 }
 
 // Get gets value by key.
-func (m ShardedMap) Get(key string) any {
+func (m *ShardedMap[K, V]) Get(key K) V {
 	shard := m.getShard(key)
 
 	shard.mu.RLock()
@@ -45,7 +89,7 @@ func (m ShardedMap) Get(key string) any {
 }
 
 // Set sets value by key.
-func (m ShardedMap) Set(key string, value any) {
+func (m *ShardedMap[K, V]) Set(key K, value V) {
 	shard := m.getShard(key)
 
 	shard.mu.Lock()
@@ -55,7 +99,7 @@ func (m ShardedMap) Set(key string, value any) {
 }
 
 // Delete deletes value buy key.
-func (m ShardedMap) Delete(key string) {
+func (m *ShardedMap[K, V]) Delete(key K) {
 	shard := m.getShard(key)
 
 	shard.mu.Lock()
@@ -64,16 +108,133 @@ func (m ShardedMap) Delete(key string) {
 	delete(shard.m, key)
 }
 
-// Keys returns all the existed keys.
-func (m ShardedMap) Keys() []string {
+// GetOrSet returns the existing value for the key if it's present. Otherwise, it sets the given value
+// and returns it. The loaded result is true if the value is loaded, false if it's set.
+func (m *ShardedMap[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	shard := m.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if actual, loaded = shard.m[key]; loaded {
+		return actual, true
+	}
+
+	shard.m[key] = value
+
+	return value, false
+}
+
+// LoadAndDelete deletes the value for the key, returning the previous value if any.
+// The loaded result reports whether the key is present.
+func (m *ShardedMap[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	shard := m.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if value, loaded = shard.m[key]; loaded {
+		delete(shard.m, key)
+	}
+
+	return value, loaded
+}
+
+// CompareAndSwap swaps the old and new values for the key if the value stored in the map
+// is equal to old. Like in sync.Map, the old value must be of a comparable type,
+// otherwise CompareAndSwap panics.
+func (m *ShardedMap[K, V]) CompareAndSwap(key K, old, new V) bool {
+	shard := m.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if cur, ok := shard.m[key]; !ok || any(cur) != any(old) {
+		return false
+	}
+
+	shard.m[key] = new
+
+	return true
+}
+
+// CompareAndDelete deletes the entry for the key if its value is equal to old.
+// Like in sync.Map, the old value must be of a comparable type, otherwise CompareAndDelete panics.
+func (m *ShardedMap[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	shard := m.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if cur, ok := shard.m[key]; !ok || any(cur) != any(old) {
+		return false
+	}
+
+	delete(shard.m, key)
+
+	return true
+}
+
+// Compute sets the value for the key computed by the function from the current value. The function
+// gets the current value and whether the key is present, and returns the new value and whether
+// to keep it: if keep is false, the key is deleted. Compute returns the resulting value and whether
+// the key is present after the call.
+//
+// The shard stays locked while the function runs, so the function must be fast and mustn't
+// access the map, otherwise it deadlocks.
+func (m *ShardedMap[K, V]) Compute(key K, fn func(value V, loaded bool) (newValue V, keep bool)) (V, bool) {
+	shard := m.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	value, loaded := shard.m[key]
+
+	value, keep := fn(value, loaded)
+	if !keep {
+		delete(shard.m, key)
+
+		var zero V
+
+		return zero, false
+	}
+
+	shard.m[key] = value
+
+	return value, true
+}
+
+// Update replaces the value for the key with the one computed by the function if the key is present.
+// It returns the new value and whether the key is present. The function runs with the shard locked,
+// just like in Compute.
+func (m *ShardedMap[K, V]) Update(key K, fn func(value V) V) (V, bool) {
+	shard := m.getShard(key)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	value, ok := shard.m[key]
+	if !ok {
+		return value, false
+	}
+
+	value = fn(value)
+	shard.m[key] = value
+
+	return value, true
+}
+
+// Keys returns all the existed keys. The shards are read one at a time, so the keys
+// don't correspond to a consistent snapshot, use Snapshot for that.
+func (m *ShardedMap[K, V]) Keys() []K {
 	var wg sync.WaitGroup
-	var keys []string
+	var keys []K
 
-	keysCh := make(chan string)
-	wg.Add(len(m))
+	keysCh := make(chan K)
+	wg.Add(len(m.shards))
 
-	for _, shard := range m {
-		go func(s *Shard) {
+	for _, shard := range m.shards {
+		go func(s *Shard[K, V]) {
 			s.mu.RLock()
 			for key := range s.m {
 				keysCh <- key
@@ -96,10 +257,201 @@ func (m ShardedMap) Keys() []string {
 	return keys
 }
 
-func (m ShardedMap) getShard(key string) *Shard {
-	checksum := sha1.Sum([]byte(key))
-	hash := int(checksum[17])
-	index := hash % len(m)
+// Range calls the function for every key and value in the map until the function returns false.
+// It reports whether all the entries are visited.
+//
+// Like sync.Map.Range, it doesn't correspond to a consistent snapshot of the whole map: the shards
+// are copied one at a time, so the entries changed concurrently may or may not be visited.
+// The function is called without the shard locked, so it may modify the map. Use Snapshot to get
+// the point-in-time copy.
+func (m *ShardedMap[K, V]) Range(fn func(key K, value V) bool) bool {
+	type entry struct {
+		key   K
+		value V
+	}
+
+	var entries []entry
+
+	for _, shard := range m.shards {
+		entries = entries[:0]
+
+		shard.mu.RLock()
+		for key, value := range shard.m {
+			entries = append(entries, entry{key: key, value: value})
+		}
+		shard.mu.RUnlock()
+
+		for _, e := range entries {
+			if !fn(e.key, e.value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Snapshot returns the point-in-time copy of all the keys and values in the map.
+// All the shards are locked for reading while the copy is made.
+func (m *ShardedMap[K, V]) Snapshot() map[K]V {
+	defer m.rlockAll()()
+
+	n := 0
+	for _, shard := range m.shards {
+		n += len(shard.m)
+	}
+
+	snapshot := make(map[K]V, n)
+
+	for _, shard := range m.shards {
+		for key, value := range shard.m {
+			snapshot[key] = value
+		}
+	}
+
+	return snapshot
+}
+
+// Len returns the number of keys in the map.
+func (m *ShardedMap[K, V]) Len() int {
+	defer m.rlockAll()()
+
+	n := 0
+	for _, shard := range m.shards {
+		n += len(shard.m)
+	}
+
+	return n
+}
+
+// rlockAll locks all the shards for reading and returns the function unlocking them.
+// The shards are always locked in the same order, and the writers lock a single shard only,
+// so it can't deadlock.
+func (m *ShardedMap[K, V]) rlockAll() func() {
+	for _, shard := range m.shards {
+		shard.mu.RLock()
+	}
+
+	return func() {
+		for _, shard := range m.shards {
+			shard.mu.RUnlock()
+		}
+	}
+}
+
+func (m *ShardedMap[K, V]) getShard(key K) *Shard[K, V] {
+	index := m.hasher(key) % uint64(len(m.shards))
+
+	return m.shards[index]
+}
+
+// defaultHasher returns the hasher with the random seed, so the different maps spread
+// the keys differently. The strings, the integers, the floats and the pointers are hashed
+// straight from their memory, including the named types based on them. The keys of other kinds,
+// e.g. the structs, arrays and interfaces, are walked with reflect, which is slower.
+func defaultHasher[K comparable]() Hasher[K] {
+	seed := maphash.MakeSeed()
+	typ := reflect.TypeOf((*K)(nil)).Elem()
+
+	switch typ.Kind() {
+	case reflect.String:
+		return func(key K) uint64 {
+			return maphash.String(seed, *(*string)(unsafe.Pointer(&key)))
+		}
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		// The equal values of these kinds have the same memory representation.
+		size := typ.Size()
+
+		return func(key K) uint64 {
+			return maphash.Bytes(seed, unsafe.Slice((*byte)(unsafe.Pointer(&key)), size))
+		}
+	case reflect.Float32:
+		return func(key K) uint64 {
+			return hashUint(seed, floatBits(float64(*(*float32)(unsafe.Pointer(&key)))))
+		}
+	case reflect.Float64:
+		return func(key K) uint64 {
+			return hashUint(seed, floatBits(*(*float64)(unsafe.Pointer(&key))))
+		}
+	default:
+		return func(key K) uint64 {
+			var h maphash.Hash
+			h.SetSeed(seed)
+			hashValue(&h, reflect.ValueOf(&key).Elem())
+
+			return h.Sum64()
+		}
+	}
+}
+
+func hashUint(seed maphash.Seed, n uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], n)
+
+	return maphash.Bytes(seed, buf[:])
+}
+
+// floatBits returns the bits of the float, treating -0 as +0, as they are equal.
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+
+	return math.Float64bits(f)
+}
+
+// hashValue writes the value to the hash so that the values equal by == produce the same hash.
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
 
-	return m[index]
+	writeUint := func(n uint64) {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		_, _ = h.Write(buf[:])
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(floatBits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeUint(floatBits(real(c)))
+		writeUint(floatBits(imag(c)))
+	case reflect.String:
+		_, _ = h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			// The blank fields don't take part in the comparison.
+			if v.Type().Field(i).Name != "_" {
+				hashValue(h, v.Field(i))
+			}
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			writeUint(0)
+			return
+		}
+
+		hashValue(h, v.Elem())
+	default:
+		// Same as comparing such values with ==.
+		panic("concurrency: hash of unhashable type " + v.Type().String())
+	}
 }
@@ -1,6 +1,10 @@
 package concurrency
 
 import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -96,3 +100,371 @@ func TestShardingDelete(t *testing.T) {
 		t.Error("Deletion failure")
 	}
 }
+
+// shardSizes returns the number of keys in every shard of the map.
+func shardSizes[K comparable, V any](m *ShardedMap[K, V]) []int {
+	sizes := make([]int, len(m.shards))
+	for i, shard := range m.shards {
+		sizes[i] = len(shard.m)
+	}
+
+	return sizes
+}
+
+// checkDistribution checks that every shard holds the number of keys close to the mean.
+func checkDistribution(t *testing.T, sizes []int, total int) {
+	t.Helper()
+
+	mean := float64(total) / float64(len(sizes))
+
+	for i, size := range sizes {
+		if math.Abs(float64(size)-mean) > mean*0.2 {
+			t.Errorf("shard %d is unbalanced: got %d keys, want %.0f ± 20%%; all shards: %v", i, size, mean, sizes)
+		}
+	}
+}
+
+// TestShardingDistribution tests that the default hasher spreads the similar keys evenly.
+func TestShardingDistribution(t *testing.T) {
+	const (
+		BUCKETS = 16
+		KEYS    = 16000
+	)
+
+	t.Run("string keys", func(t *testing.T) {
+		sMap := NewShardedMap(BUCKETS)
+		for i := 0; i < KEYS; i++ {
+			sMap.Set(fmt.Sprintf("user:%d", i), i)
+		}
+
+		checkDistribution(t, shardSizes(sMap), KEYS)
+	})
+
+	t.Run("int keys", func(t *testing.T) {
+		// The keys are multiples of the number of shards, so a plain modulo would put them all into one shard.
+		sMap := NewShardedMapOf[int, int](BUCKETS)
+		for i := 0; i < KEYS; i++ {
+			sMap.Set(i*BUCKETS, i)
+		}
+
+		checkDistribution(t, shardSizes(sMap), KEYS)
+	})
+
+	t.Run("struct keys", func(t *testing.T) {
+		type point struct{ x, y int }
+
+		sMap := NewShardedMapOf[point, int](BUCKETS)
+		for i := 0; i < KEYS; i++ {
+			sMap.Set(point{x: i % 128, y: i / 128}, i)
+		}
+
+		checkDistribution(t, shardSizes(sMap), KEYS)
+	})
+}
+
+// TestShardingGeneric tests the map with non-string keys and a custom hasher.
+func TestShardingGeneric(t *testing.T) {
+	const BUCKETS = 4
+
+	var calls int
+
+	sMap := NewShardedMapOf[float64, string](BUCKETS, WithHasher(func(key float64) uint64 {
+		calls++
+		return uint64(key)
+	}))
+
+	sMap.Set(1.5, "one and a half")
+	sMap.Set(2, "two")
+
+	if got := sMap.Get(1.5); got != "one and a half" {
+		t.Errorf("Key mismatch on 1.5: expected %q, got %q", "one and a half", got)
+	}
+
+	if calls != 3 {
+		t.Errorf("custom hasher isn't used: got %d calls, want 3", calls)
+	}
+
+	if sizes := shardSizes(sMap); !reflect.DeepEqual(sizes, []int{0, 1, 1, 0}) {
+		t.Errorf("keys aren't placed by the custom hasher: got shard sizes %v", sizes)
+	}
+
+	// The default hasher treats the negative zero as the positive one, like the map does.
+	zeros := NewShardedMapOf[float64, string](BUCKETS)
+	zeros.Set(math.Copysign(0, -1), "zero")
+
+	if got := zeros.Get(0); got != "zero" {
+		t.Errorf("Key mismatch on 0: expected %q, got %q", "zero", got)
+	}
+}
+
+// TestShardingCompute tests that concurrent Compute and Update calls don't lose increments.
+func TestShardingCompute(t *testing.T) {
+	const (
+		BUCKETS    = 4
+		GOROUTINES = 8
+		INCREMENTS = 1000
+	)
+
+	sMap := NewShardedMapOf[string, int](BUCKETS)
+	sMap.Set("updated", 0)
+
+	var wg sync.WaitGroup
+
+	wg.Add(GOROUTINES)
+
+	for i := 0; i < GOROUTINES; i++ {
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < INCREMENTS; j++ {
+				sMap.Compute("computed", func(value int, _ bool) (int, bool) {
+					return value + 1, true
+				})
+				sMap.Update("updated", func(value int) int {
+					return value + 1
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, key := range []string{"computed", "updated"} {
+		if got := sMap.Get(key); got != GOROUTINES*INCREMENTS {
+			t.Errorf("Lost increments on %s: expected %d, got %d", key, GOROUTINES*INCREMENTS, got)
+		}
+	}
+
+	if got, ok := sMap.Compute("computed", func(int, bool) (int, bool) { return 0, false }); ok || got != 0 {
+		t.Errorf("Compute doesn't delete the key: got (%d, %t)", got, ok)
+	}
+
+	if _, ok := sMap.Update("computed", func(value int) int { return value + 1 }); ok {
+		t.Error("Update sets the absent key")
+	}
+
+	if keys := sMap.Keys(); !reflect.DeepEqual(keys, []string{"updated"}) {
+		t.Errorf("Key mismatch: expected [updated], got %v", keys)
+	}
+}
+
+// TestShardingAtomicOps tests the semantics of the operations that match sync.Map.
+func TestShardingAtomicOps(t *testing.T) {
+	sMap := NewShardedMapOf[string, int](4)
+
+	if actual, loaded := sMap.GetOrSet("alpha", 1); loaded || actual != 1 {
+		t.Errorf("GetOrSet on the absent key: got (%d, %t), want (1, false)", actual, loaded)
+	}
+
+	if actual, loaded := sMap.GetOrSet("alpha", 2); !loaded || actual != 1 {
+		t.Errorf("GetOrSet on the present key: got (%d, %t), want (1, true)", actual, loaded)
+	}
+
+	if sMap.CompareAndSwap("alpha", 2, 3) {
+		t.Error("CompareAndSwap swaps the mismatched value")
+	}
+
+	if sMap.CompareAndSwap("beta", 0, 3) {
+		t.Error("CompareAndSwap swaps the absent key")
+	}
+
+	if !sMap.CompareAndSwap("alpha", 1, 3) || sMap.Get("alpha") != 3 {
+		t.Errorf("CompareAndSwap doesn't swap the matched value: got %d", sMap.Get("alpha"))
+	}
+
+	if sMap.CompareAndDelete("alpha", 1) {
+		t.Error("CompareAndDelete deletes the mismatched value")
+	}
+
+	if !sMap.CompareAndDelete("alpha", 3) {
+		t.Error("CompareAndDelete doesn't delete the matched value")
+	}
+
+	sMap.Set("gamma", 5)
+
+	if value, loaded := sMap.LoadAndDelete("gamma"); !loaded || value != 5 {
+		t.Errorf("LoadAndDelete on the present key: got (%d, %t), want (5, true)", value, loaded)
+	}
+
+	if value, loaded := sMap.LoadAndDelete("gamma"); loaded || value != 0 {
+		t.Errorf("LoadAndDelete on the absent key: got (%d, %t), want (0, false)", value, loaded)
+	}
+
+	if len(sMap.Keys()) != 0 {
+		t.Error("Deletion failure")
+	}
+}
+
+// TestShardingCompareIncomparable tests that CompareAndSwap panics on the incomparable values like sync.Map does.
+func TestShardingCompareIncomparable(t *testing.T) {
+	sMap := NewShardedMap(4)
+	sMap.Set("slice", []int{1})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("CompareAndSwap doesn't panic on the incomparable value")
+		}
+	}()
+
+	sMap.CompareAndSwap("slice", []int{1}, []int{2})
+}
+
+// TestShardingRange tests that Range visits every entry once and stops early.
+func TestShardingRange(t *testing.T) {
+	const BUCKETS = 17
+
+	sMap := NewShardedMapOf[string, int](BUCKETS)
+
+	truthMap := map[string]int{
+		"alpha":   1,
+		"beta":    2,
+		"gamma":   3,
+		"delta":   4,
+		"epsilon": 5,
+	}
+
+	for k, v := range truthMap {
+		sMap.Set(k, v)
+	}
+
+	if sMap.Len() != len(truthMap) {
+		t.Errorf("Len mismatch: expected %d, got %d", len(truthMap), sMap.Len())
+	}
+
+	visited := make(map[string]int)
+
+	// The function may modify the map.
+	all := sMap.Range(func(key string, value int) bool {
+		visited[key] = value
+		sMap.Set(key, value*10)
+
+		return true
+	})
+
+	if !all || !reflect.DeepEqual(visited, truthMap) {
+		t.Errorf("Range mismatch: expected %v, got %v", truthMap, visited)
+	}
+
+	var calls int
+
+	if sMap.Range(func(string, int) bool {
+		calls++
+		return calls < 2
+	}) {
+		t.Error("Range reports visiting all the entries after stopping")
+	}
+
+	if calls != 2 {
+		t.Errorf("Range doesn't stop early: expected 2 calls, got %d", calls)
+	}
+}
+
+// TestShardingSnapshot tests that Snapshot is the point-in-time copy. The writer updates the keys
+// in order, so any consistent snapshot has the generations non-increasing along the keys.
+func TestShardingSnapshot(t *testing.T) {
+	const (
+		BUCKETS     = 8
+		KEYS        = 32
+		GENERATIONS = 500
+	)
+
+	sMap := NewShardedMapOf[int, int](BUCKETS)
+	for i := 0; i < KEYS; i++ {
+		sMap.Set(i, 0)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for g := 1; g <= GENERATIONS; g++ {
+			for i := 0; i < KEYS; i++ {
+				sMap.Set(i, g)
+			}
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		snapshot := sMap.Snapshot()
+		if len(snapshot) != KEYS {
+			t.Fatalf("Snapshot size mismatch: expected %d, got %d", KEYS, len(snapshot))
+		}
+
+		for i := 1; i < KEYS; i++ {
+			if prev, cur := snapshot[i-1], snapshot[i]; cur > prev || prev-cur > 1 {
+				t.Fatalf("Snapshot is inconsistent: key %d has generation %d, key %d has %d", i-1, prev, i, cur)
+			}
+		}
+	}
+
+	snapshot := sMap.Snapshot()
+
+	// The snapshot is the copy, the changes of the map don't affect it.
+	sMap.Delete(0)
+
+	if snapshot[0] != GENERATIONS || sMap.Len() != KEYS-1 {
+		t.Errorf("Snapshot isn't the copy: got %d, map size %d", snapshot[0], sMap.Len())
+	}
+}
+
+// TestShardingEqualKeys tests that the keys equal by == are found by each other,
+// including the composite keys holding -0 and +0.
+func TestShardingEqualKeys(t *testing.T) {
+	const BUCKETS = 64
+
+	type point struct {
+		X, Y float64
+		name string
+	}
+
+	negZero := math.Copysign(0, -1)
+
+	t.Run("struct keys", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			sMap := NewShardedMapOf[point, int](BUCKETS)
+			sMap.Set(point{X: negZero, Y: 1, name: "p"}, 1)
+
+			if _, loaded := sMap.LoadAndDelete(point{X: 0, Y: 1, name: "p"}); !loaded {
+				t.Fatal("Key mismatch on the struct with -0")
+			}
+		}
+	})
+
+	t.Run("interface keys", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			sMap := NewShardedMapOf[any, int](BUCKETS)
+			sMap.Set(negZero, 1)
+			sMap.Set([2]any{negZero, "a"}, 2)
+
+			if got := sMap.Get(0.0); got != 1 {
+				t.Fatalf("Key mismatch on -0 in the interface: expected 1, got %d", got)
+			}
+
+			if got := sMap.Get([2]any{0.0, "a"}); got != 2 {
+				t.Fatalf("Key mismatch on -0 in the array: expected 2, got %d", got)
+			}
+		}
+	})
+
+	t.Run("named keys", func(t *testing.T) {
+		type userID string
+
+		sMap := NewShardedMapOf[userID, int](16)
+		for i := 0; i < 16000; i++ {
+			sMap.Set(userID(fmt.Sprintf("user:%d", i)), i)
+		}
+
+		if got := sMap.Get("user:42"); got != 42 {
+			t.Errorf("Key mismatch on user:42: expected 42, got %d", got)
+		}
+
+		checkDistribution(t, shardSizes(sMap), 16000)
+	})
+}
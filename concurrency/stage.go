@@ -0,0 +1,160 @@
+package concurrency
+
+import "sync"
+
+type stageConfig struct {
+	workers     int
+	ordered     bool
+	probe       stageProbe
+	deadLetters DeadLetterHandler
+}
+
+// StageOption configures the stage built by Stage.
+type StageOption func(cfg *stageConfig)
+
+// Workers sets the number of goroutines processing the values of the stage concurrently.
+// Default is 1, the stage has at least one worker.
+func Workers(n int) StageOption {
+	return func(cfg *stageConfig) {
+		cfg.workers = n
+	}
+}
+
+// Ordered makes the stage with several workers emit the results in the order of the input values.
+// Otherwise, they are emitted in the order the workers finish them.
+func Ordered() StageOption {
+	return func(cfg *stageConfig) {
+		cfg.ordered = true
+	}
+}
+
+// DeadLetters makes the stage recover the panics of its function. The value that has made
+// the function panic is passed to the handler and skipped, the stage continues with the next values.
+func DeadLetters(h DeadLetterHandler) StageOption {
+	return func(cfg *stageConfig) {
+		cfg.deadLetters = h
+	}
+}
+
+// Stage builds Job for Pipeline that applies the function to every input value.
+// Unlike a hand-written Job running on a single goroutine, the stage can process
+// the values on several workers, so a CPU-heavy stage doesn't bottleneck the whole Pipeline:
+//
+//	in, out := Pipeline(parse, Stage(resize, Workers(8), Ordered()), store)
+//
+// To keep the order, every value gets a sequence number and the results that are ready out
+// of order wait in the reorder buffer until the preceding ones are emitted. At most twice as many
+// values as workers are processed or buffered at once, so a slow value holds back the stage rather
+// than lets the buffer grow.
+func Stage(fn WorkerFunc, opts ...StageOption) Job {
+	cfg := stageConfig{workers: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.workers < 1 {
+		cfg.workers = 1
+	}
+
+	call := func(val any) (any, bool) {
+		return safeCall(fn, val, cfg.deadLetters)
+	}
+
+	if cfg.ordered && cfg.workers > 1 {
+		return func(in <-chan any, out chan<- any) {
+			runOrderedStage(call, cfg.workers, cfg.probe, in, out)
+		}
+	}
+
+	return func(in <-chan any, out chan<- any) {
+		var wg sync.WaitGroup
+		wg.Add(cfg.workers)
+
+		for i := 0; i < cfg.workers; i++ {
+			go func() {
+				defer wg.Done()
+
+				for val, ok := cfg.probe.recv(in); ok; val, ok = cfg.probe.recv(in) {
+					if res, ok := cfg.probe.process(call, val); ok {
+						cfg.probe.send(out, res)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+	}
+}
+
+type seqValue struct {
+	seq int
+	val any
+	// skip is set if the value has failed and mustn't be emitted.
+	skip bool
+}
+
+func runOrderedStage(call func(val any) (any, bool), workers int, probe stageProbe, in <-chan any, out chan<- any) {
+	tasks := make(chan seqValue)
+	results := make(chan seqValue)
+	// window bounds the number of values that are processed or wait in the reorder buffer.
+	window := make(chan struct{}, 2*workers)
+
+	go func() {
+		defer close(tasks)
+
+		var seq int
+
+		for val, ok := probe.recv(in); ok; val, ok = probe.recv(in) {
+			window <- struct{}{}
+			tasks <- seqValue{seq: seq, val: val}
+			seq++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for task := range tasks {
+				res, ok := probe.process(call, task.val)
+				results <- seqValue{seq: task.seq, val: res, skip: !ok}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	reorder(results, func(val any) {
+		probe.send(out, val)
+	}, func() {
+		<-window
+	})
+}
+
+// reorder emits the values from the results channel in the order of their sequence numbers,
+// which start from 0. The values that came out of order wait in the buffer for the preceding ones.
+// The release function is called for every value that leaves the buffer including the skipped ones.
+func reorder(results <-chan seqValue, emit func(val any), release func()) {
+	var next int
+	pending := make(map[int]seqValue)
+
+	for res := range results {
+		pending[res.seq] = res
+
+		for res, ok := pending[next]; ok; res, ok = pending[next] {
+			if !res.skip {
+				emit(res.val)
+			}
+
+			delete(pending, next)
+			next++
+			release()
+		}
+	}
+}
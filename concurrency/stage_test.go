@@ -0,0 +1,142 @@
+package concurrency
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func sleepyIncr(in any) any {
+	time.Sleep(time.Duration(rand.Intn(20)) * time.Millisecond)
+	return in.(int) + 1
+}
+
+// runPipeline sends the values to the pipeline and collects all its results.
+func runPipeline(in chan<- any, out <-chan any, vals []int) []int {
+	go func() {
+		for _, val := range vals {
+			in <- val
+		}
+
+		close(in)
+	}()
+
+	var res []int
+	for val := range out {
+		res = append(res, val.(int))
+	}
+
+	return res
+}
+
+func TestStage(t *testing.T) {
+	vals := make([]int, 40)
+	want := make([]int, len(vals))
+
+	for i := range vals {
+		vals[i] = i
+		want[i] = i + 2
+	}
+
+	testCases := []struct {
+		name    string
+		opts    []StageOption
+		ordered bool
+	}{
+		{name: "single worker", ordered: true},
+		{name: "parallel", opts: []StageOption{Workers(8)}},
+		{name: "parallel ordered", opts: []StageOption{Workers(8), Ordered()}, ordered: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testutils.DetectGoroutineLeeks(t, func() {
+				in, out := Pipeline(Stage(sleepyIncr, tc.opts...), incrJob)
+				got := runPipeline(in, out, vals)
+
+				if !tc.ordered {
+					sort.Ints(got)
+				}
+
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("wrong pipeline result: got %v, want %v", got, want)
+				}
+			})
+		})
+	}
+}
+
+func TestStageWorkersRunConcurrently(t *testing.T) {
+	const workers = 8
+
+	sleep := func(in any) any {
+		time.Sleep(50 * time.Millisecond)
+		return in
+	}
+
+	start := time.Now()
+	in, out := Pipeline(Stage(sleep, Workers(workers), Ordered()))
+	runPipeline(in, out, make([]int, workers))
+
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("workers don't run concurrently: took %v", elapsed)
+	}
+}
+
+func TestStageNonPositiveWorkers(t *testing.T) {
+	for _, workers := range []int{0, -1} {
+		workers := workers
+
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			testutils.DetectGoroutineLeeks(t, func() {
+				in, out := Pipeline(Stage(sleepyIncr, Workers(workers)))
+
+				if got, want := runPipeline(in, out, []int{1, 2, 3}), []int{2, 3, 4}; !reflect.DeepEqual(got, want) {
+					t.Errorf("wrong stage result: got %v, want %v", got, want)
+				}
+			})
+		})
+	}
+}
+
+func heavyHash(in any) any {
+	sum := sha256.Sum256([]byte{byte(in.(int))})
+	for i := 0; i < 1000; i++ {
+		sum = sha256.Sum256(sum[:])
+	}
+
+	return int(sum[0])
+}
+
+func benchmarkStage(b *testing.B, opts ...StageOption) {
+	in, out := Pipeline(Stage(heavyHash, opts...))
+
+	go func() {
+		for i := 0; i < b.N; i++ {
+			in <- i
+		}
+
+		close(in)
+	}()
+
+	for range out {
+	}
+}
+
+func BenchmarkStageSerial(b *testing.B) {
+	benchmarkStage(b)
+}
+
+func BenchmarkStageParallel(b *testing.B) {
+	benchmarkStage(b, Workers(8))
+}
+
+func BenchmarkStageParallelOrdered(b *testing.B) {
+	benchmarkStage(b, Workers(8), Ordered())
+}
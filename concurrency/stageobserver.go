@@ -0,0 +1,238 @@
+package concurrency
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// StageObserver receives the measurements of the stages built by Stage with Observe option
+// and of the jobs wrapped by ObserveJob.
+// It's called concurrently by the workers of the stage, and may be shared among several stages,
+// so the implementation must be safe for concurrent use.
+type StageObserver interface {
+	// ItemIn is called when the stage receives a value, blocked is how long it has waited for the value.
+	ItemIn(stage string, blocked time.Duration)
+	// ItemProcessed is called when the stage's function returns the result for the value.
+	ItemProcessed(stage string, latency time.Duration)
+	// ItemOut is called when the stage sends the result, blocked is how long it has waited
+	// for the next stage to receive it.
+	ItemOut(stage string, blocked time.Duration)
+}
+
+// Observe makes the stage report its measurements under the given name to the observer.
+// Use ObserveJob for the jobs not built by Stage.
+func Observe(name string, o StageObserver) StageOption {
+	return func(cfg *stageConfig) {
+		cfg.probe = stageProbe{name: name, observer: o}
+	}
+}
+
+// ObserveJob wraps Job to report its measurements under the given name to the observer.
+// The job's channels are proxied, so only the waiting on them is measured: ItemIn and ItemOut
+// are reported as for a stage, but ItemProcessed isn't, since a plain job doesn't have to produce
+// one result per value. The proxies add a value of buffering on each side of the job.
+//
+// If the job returns before its input is closed, the rest of the input is drained and dropped,
+// so the previous stage doesn't block. The nil observer means no measurements, the job
+// is returned as is.
+func ObserveJob(name string, o StageObserver, job Job) Job {
+	if o == nil {
+		return job
+	}
+
+	probe := stageProbe{name: name, observer: o}
+
+	return func(in <-chan any, out chan<- any) {
+		jobIn := make(chan any)
+		jobOut := make(chan any)
+		done := make(chan struct{})
+		sent := make(chan struct{})
+
+		go func() {
+			defer close(jobIn)
+
+			for {
+				val, ok := probe.recv(in)
+				if !ok {
+					return
+				}
+
+				select {
+				case jobIn <- val:
+				case <-done:
+					for range in {
+					}
+
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer close(sent)
+
+			for val := range jobOut {
+				probe.send(out, val)
+			}
+		}()
+
+		job(jobIn, jobOut)
+		close(done)
+		close(jobOut)
+		<-sent
+	}
+}
+
+// stageProbe measures the stage's operations if the observer is set.
+type stageProbe struct {
+	name     string
+	observer StageObserver
+}
+
+func (p stageProbe) recv(in <-chan any) (any, bool) {
+	if p.observer == nil {
+		val, ok := <-in
+		return val, ok
+	}
+
+	start := time.Now()
+
+	val, ok := <-in
+	if ok {
+		p.observer.ItemIn(p.name, time.Since(start))
+	}
+
+	return val, ok
+}
+
+func (p stageProbe) process(call func(val any) (any, bool), val any) (any, bool) {
+	if p.observer == nil {
+		return call(val)
+	}
+
+	start := time.Now()
+	res, ok := call(val)
+	p.observer.ItemProcessed(p.name, time.Since(start))
+
+	return res, ok
+}
+
+func (p stageProbe) send(out chan<- any, val any) {
+	if p.observer == nil {
+		out <- val
+		return
+	}
+
+	start := time.Now()
+	out <- val
+	p.observer.ItemOut(p.name, time.Since(start))
+}
+
+// DefaultLatencyBuckets are the upper bounds of the latency histogram buckets used by MemoryObserver by default.
+var DefaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// Histogram counts the observed durations in the buckets. Counts[i] is the number of durations
+// not greater than Bounds[i] and greater than the previous bound, the last element of Counts
+// is the number of durations greater than all the bounds.
+type Histogram struct {
+	Bounds []time.Duration
+	Counts []int64
+	Total  int64
+	Sum    time.Duration
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	i := sort.Search(len(h.Bounds), func(i int) bool { return d <= h.Bounds[i] })
+	h.Counts[i]++
+	h.Total++
+	h.Sum += d
+}
+
+// StageStats holds the measurements of a single stage collected by MemoryObserver.
+type StageStats struct {
+	ItemsIn          int64
+	ItemsOut         int64
+	Latency          Histogram
+	BlockedOnReceive time.Duration
+	BlockedOnSend    time.Duration
+}
+
+// MemoryObserver is StageObserver that collects the measurements in memory.
+// It's mostly useful in tests and for ad-hoc debugging of the slow pipelines.
+type MemoryObserver struct {
+	buckets []time.Duration
+
+	mu     sync.Mutex
+	stages map[string]*StageStats
+}
+
+// NewMemoryObserver constructs MemoryObserver. It takes the ascending upper bounds of the latency
+// histogram buckets, if none are given, DefaultLatencyBuckets are used.
+func NewMemoryObserver(buckets ...time.Duration) *MemoryObserver {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
+	return &MemoryObserver{
+		buckets: buckets,
+		stages:  make(map[string]*StageStats),
+	}
+}
+
+// Stats returns the copy of the stage's measurements collected so far.
+func (o *MemoryObserver) Stats(stage string) StageStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := *o.stage(stage)
+	stats.Latency.Counts = append([]int64(nil), stats.Latency.Counts...)
+
+	return stats
+}
+
+func (o *MemoryObserver) ItemIn(stage string, blocked time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.stage(stage)
+	s.ItemsIn++
+	s.BlockedOnReceive += blocked
+}
+
+func (o *MemoryObserver) ItemProcessed(stage string, latency time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.stage(stage).Latency.observe(latency)
+}
+
+func (o *MemoryObserver) ItemOut(stage string, blocked time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.stage(stage)
+	s.ItemsOut++
+	s.BlockedOnSend += blocked
+}
+
+func (o *MemoryObserver) stage(name string) *StageStats {
+	s, ok := o.stages[name]
+	if !ok {
+		s = &StageStats{
+			Latency: Histogram{
+				Bounds: o.buckets,
+				Counts: make([]int64, len(o.buckets)+1),
+			},
+		}
+		o.stages[name] = s
+	}
+
+	return s
+}
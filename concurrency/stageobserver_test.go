@@ -0,0 +1,132 @@
+package concurrency
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func TestStageObserver(t *testing.T) {
+	const n = 5
+
+	sleep := func(in any) any {
+		time.Sleep(5 * time.Millisecond)
+		return in
+	}
+
+	obs := NewMemoryObserver()
+	in, out := Pipeline(
+		Stage(sleep, Observe("sleep", obs)),
+		Stage(sleep, Workers(2), Ordered(), Observe("sleep-ordered", obs)),
+	)
+
+	go func() {
+		for i := 0; i < n; i++ {
+			in <- i
+		}
+
+		close(in)
+	}()
+
+	// The slow consumer makes the last stage wait on sending.
+	for range out {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	for _, name := range []string{"sleep", "sleep-ordered"} {
+		stats := obs.Stats(name)
+
+		if stats.ItemsIn != n || stats.ItemsOut != n {
+			t.Errorf("%s: wrong number of items: got %d in, %d out, want %d", name, stats.ItemsIn, stats.ItemsOut, n)
+		}
+
+		// No latency falls into the buckets up to 1ms.
+		if stats.Latency.Total != n || !reflect.DeepEqual(stats.Latency.Counts[:2], []int64{0, 0}) {
+			t.Errorf("%s: wrong latency histogram: got %v", name, stats.Latency.Counts)
+		}
+	}
+
+	if blocked := obs.Stats("sleep-ordered").BlockedOnSend; blocked < 50*time.Millisecond {
+		t.Errorf("last stage is blocked on sending only for %v", blocked)
+	}
+}
+
+func TestObserveJob(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		// pairs sums the values pairwise, so it doesn't produce a result per value.
+		pairs := func(in <-chan any, out chan<- any) {
+			for a := range in {
+				b, ok := <-in
+				if !ok {
+					out <- a
+					return
+				}
+
+				out <- a.(int) + b.(int)
+			}
+		}
+
+		obs := NewMemoryObserver()
+		in, out := Pipeline(ObserveJob("pairs", obs, pairs))
+
+		go func() {
+			for i := 1; i <= 5; i++ {
+				in <- i
+			}
+
+			close(in)
+		}()
+
+		var res []any
+		for val := range out {
+			res = append(res, val)
+		}
+
+		if want := []any{3, 7, 5}; !reflect.DeepEqual(res, want) {
+			t.Errorf("wrong results: got %v, want %v", res, want)
+		}
+
+		if stats := obs.Stats("pairs"); stats.ItemsIn != 5 || stats.ItemsOut != 3 || stats.Latency.Total != 0 {
+			t.Errorf("wrong stats: got %d in, %d out, %d processed", stats.ItemsIn, stats.ItemsOut, stats.Latency.Total)
+		}
+	})
+}
+
+func TestObserveJobReturnsEarly(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		// first takes a single value and returns without reading the rest.
+		first := func(in <-chan any, out chan<- any) {
+			out <- <-in
+		}
+
+		in, out := Pipeline(ObserveJob("first", NewMemoryObserver(), first))
+		sent := make(chan struct{})
+
+		go func() {
+			defer close(sent)
+
+			for i := 1; i <= 5; i++ {
+				in <- i
+			}
+
+			close(in)
+		}()
+
+		var res []any
+		for val := range out {
+			res = append(res, val)
+		}
+
+		if want := []any{1}; !reflect.DeepEqual(res, want) {
+			t.Errorf("wrong results: got %v, want %v", res, want)
+		}
+
+		select {
+		case <-sent:
+		case <-time.After(time.Second):
+			t.Error("sender is blocked after the job has returned")
+		}
+	})
+}
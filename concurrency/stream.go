@@ -0,0 +1,205 @@
+package concurrency
+
+import "context"
+
+// The operators below compose the streams of values, such as the output of Generator.
+// Each of them reads the input channel in a separate goroutine and returns the output channel.
+// The output is closed and the goroutine exits when the input is closed or the context is done.
+// An operator that stops reading the input early, like Take, doesn't stop the producer
+// of the input, so it's expected to be canceled through the same context.
+
+// Pair holds the values zipped together by Zip.
+type Pair[T, U any] struct {
+	First  T
+	Second U
+}
+
+// FromSlice emits the values of the slice.
+func FromSlice[T any](ctx context.Context, vals []T) <-chan T {
+	var i int
+
+	return GeneratorOf(ctx, func() (T, bool) {
+		if i == len(vals) {
+			var zero T
+			return zero, false
+		}
+
+		val := vals[i]
+		i++
+
+		return val, true
+	})
+}
+
+// Repeat emits the values over and over again until the context is done.
+// If no values are given, the output is closed straightaway.
+func Repeat[T any](ctx context.Context, vals ...T) <-chan T {
+	var i int
+
+	return GeneratorOf(ctx, func() (T, bool) {
+		if len(vals) == 0 {
+			var zero T
+			return zero, false
+		}
+
+		val := vals[i%len(vals)]
+		i++
+
+		return val, true
+	})
+}
+
+// Take emits the first n values of the input.
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for i := 0; i < n; i++ {
+			val, ok, done := recvContext(ctx, in)
+			if !ok || done || !sendContext(ctx, out, val) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Skip discards the first n values of the input and emits the rest.
+func Skip[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+	var skipped int
+
+	return Filter(ctx, in, func(T) bool {
+		if skipped < n {
+			skipped++
+			return false
+		}
+
+		return true
+	})
+}
+
+// Filter emits only the values of the input that satisfy the predicate.
+func Filter[T any](ctx context.Context, in <-chan T, pred func(val T) bool) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for {
+			val, ok, done := recvContext(ctx, in)
+			if !ok || done {
+				return
+			}
+
+			if pred(val) && !sendContext(ctx, out, val) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Transform emits the result of fn for every value of the input. It's the map operator,
+// named differently since Map is the Future combinator.
+func Transform[T, U any](ctx context.Context, in <-chan T, fn func(val T) U) <-chan U {
+	out := make(chan U)
+
+	go func() {
+		defer close(out)
+
+		for {
+			val, ok, done := recvContext(ctx, in)
+			if !ok || done || !sendContext(ctx, out, fn(val)) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Chunk groups the values of the input into slices of the given size. When the input is closed,
+// the rest of the values is emitted as the last, shorter chunk. It panics if the size isn't positive.
+func Chunk[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
+	if size <= 0 {
+		panic("concurrency: non-positive chunk size")
+	}
+
+	out := make(chan []T)
+
+	go func() {
+		defer close(out)
+
+		chunk := make([]T, 0, size)
+
+		for {
+			val, ok, done := recvContext(ctx, in)
+			if done {
+				return
+			}
+
+			if !ok {
+				if len(chunk) > 0 {
+					sendContext(ctx, out, chunk)
+				}
+
+				return
+			}
+
+			chunk = append(chunk, val)
+			if len(chunk) < size {
+				continue
+			}
+
+			if !sendContext(ctx, out, chunk) {
+				return
+			}
+
+			chunk = make([]T, 0, size)
+		}
+	}()
+
+	return out
+}
+
+// Zip pairs up the values of two inputs in the order they come.
+// It stops when either of the inputs is closed.
+func Zip[T, U any](ctx context.Context, first <-chan T, second <-chan U) <-chan Pair[T, U] {
+	out := make(chan Pair[T, U])
+
+	go func() {
+		defer close(out)
+
+		for {
+			a, ok, done := recvContext(ctx, first)
+			if !ok || done {
+				return
+			}
+
+			b, ok, done := recvContext(ctx, second)
+			if !ok || done {
+				return
+			}
+
+			if !sendContext(ctx, out, Pair[T, U]{First: a, Second: b}) {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// sendContext sends the value to the channel. It returns false if the context is done first.
+func sendContext[T any](ctx context.Context, ch chan<- T, val T) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case ch <- val:
+		return true
+	}
+}
@@ -0,0 +1,118 @@
+package concurrency
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func collect[T any](ch <-chan T) []T {
+	var vals []T
+	for val := range ch {
+		vals = append(vals, val)
+	}
+
+	return vals
+}
+
+func TestStreamOperators(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	isEven := func(val int) bool { return val%2 == 0 }
+
+	testCases := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{
+			name: "from slice",
+			got:  collect(FromSlice(ctx, []string{"a", "b"})),
+			want: []string{"a", "b"},
+		},
+		{
+			name: "take",
+			got:  collect(Take(ctx, Generator(ctx), 3)),
+			want: []int{0, 1, 2},
+		},
+		{
+			name: "skip",
+			got:  collect(Take(ctx, Skip(ctx, Generator(ctx), 3), 2)),
+			want: []int{3, 4},
+		},
+		{
+			name: "filter",
+			got:  collect(Take(ctx, Filter(ctx, Generator(ctx), isEven), 3)),
+			want: []int{0, 2, 4},
+		},
+		{
+			name: "transform",
+			got:  collect(Transform(ctx, FromSlice(ctx, []int{1, 2}), strconv.Itoa)),
+			want: []string{"1", "2"},
+		},
+		{
+			name: "chunk",
+			got:  collect(Chunk(ctx, Take(ctx, Generator(ctx), 5), 2)),
+			want: [][]int{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			name: "zip",
+			got:  collect(Zip(ctx, Generator(ctx), FromSlice(ctx, []string{"a", "b"}))),
+			want: []Pair[int, string]{{First: 0, Second: "a"}, {First: 1, Second: "b"}},
+		},
+		{
+			name: "repeat",
+			got:  collect(Take(ctx, Repeat(ctx, "x", "y"), 5)),
+			want: []string{"x", "y", "x", "y", "x"},
+		},
+	}
+
+	for _, tc := range testCases {
+		if !reflect.DeepEqual(tc.got, tc.want) {
+			t.Errorf("wrong %s result: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestStreamOperatorsCancel(t *testing.T) {
+	testutils.DetectGoroutineLeeksWithGrace(t, leekGracePeriod, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		pairs := Zip(ctx, Generator(ctx), Repeat(ctx, "x"))
+		pairs = Filter(ctx, Skip(ctx, pairs, 1), func(p Pair[int, string]) bool {
+			return p.First%2 == 0
+		})
+		squares := Transform(ctx, pairs, func(p Pair[int, string]) int {
+			return p.First * p.First
+		})
+		out := Chunk(ctx, squares, 2)
+
+		if got, want := <-out, []int{4, 16}; !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong result: got %v, want %v", got, want)
+		}
+
+		// Every operator is blocked on sending or receiving now.
+		cancel()
+
+		for range out {
+		}
+	})
+}
+
+func TestChunkInvalidSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Chunk doesn't panic on size %d", size)
+				}
+			}()
+
+			Chunk(context.Background(), make(chan int), size)
+		}()
+	}
+}
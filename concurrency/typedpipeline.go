@@ -0,0 +1,57 @@
+package concurrency
+
+// TypedJob is the typed version of Job, it receives values of type In and sends values of type Out.
+type TypedJob[In, Out any] func(in <-chan In, out chan<- Out)
+
+// PipelineBuilder builds the typed version of Pipeline, which receives values of type In
+// and emits the results of type Out. Since every stage is TypedJob, the types of adjacent stages
+// are checked at compile time instead of panicking in the type assertions at runtime.
+//
+// Go methods can't have their own type parameters, so the stage that changes the type of values
+// is appended by AddStage function, while Then method appends the stage that keeps it:
+//
+//	b := NewPipeline[string]().Then(trim)
+//	in, out := AddStage(AddStage(b, parseInt), formatPrice).Build()
+//
+// PipelineBuilder is immutable, appending a stage returns the new builder.
+type PipelineBuilder[In, Out any] struct {
+	connect func(in <-chan In) <-chan Out
+}
+
+// NewPipeline constructs PipelineBuilder without stages, which receives and emits values of type T.
+func NewPipeline[T any]() *PipelineBuilder[T, T] {
+	return &PipelineBuilder[T, T]{
+		connect: func(in <-chan T) <-chan T { return in },
+	}
+}
+
+// Then appends the stage that emits values of the same type.
+func (b *PipelineBuilder[In, Out]) Then(job TypedJob[Out, Out]) *PipelineBuilder[In, Out] {
+	return AddStage(b, job)
+}
+
+// AddStage appends the stage that receives the results of the builder's last stage
+// and emits values of another type.
+func AddStage[In, Mid, Out any](b *PipelineBuilder[In, Mid], job TypedJob[Mid, Out]) *PipelineBuilder[In, Out] {
+	return &PipelineBuilder[In, Out]{
+		connect: func(in <-chan In) <-chan Out {
+			mid := b.connect(in)
+			out := make(chan Out)
+
+			go func() {
+				job(mid, out)
+				close(out)
+			}()
+
+			return out
+		},
+	}
+}
+
+// Build starts the stages and returns two channels like Pipeline does: the first one is for sending
+// values to the pipeline, the second one is for receiving the results. Closing the sending channel
+// finishes the pipeline. Every call starts the new set of stages.
+func (b *PipelineBuilder[In, Out]) Build() (chan<- In, <-chan Out) {
+	in := make(chan In)
+	return in, b.connect(in)
+}
@@ -0,0 +1,70 @@
+package concurrency
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+func trimJob(in <-chan string, out chan<- string) {
+	for val := range in {
+		out <- strings.TrimSpace(val)
+	}
+}
+
+func atoiJob(in <-chan string, out chan<- int) {
+	for val := range in {
+		n, _ := strconv.Atoi(val)
+		out <- n
+	}
+}
+
+func doubleJob(in <-chan int, out chan<- int) {
+	for val := range in {
+		out <- val * 2
+	}
+}
+
+func TestPipelineBuilder(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		b := AddStage(NewPipeline[string]().Then(trimJob), atoiJob).Then(doubleJob)
+		in, out := b.Build()
+
+		select {
+		case in <- " 21 ":
+		case <-time.After(50 * time.Millisecond):
+			t.Fatal("timeout exceeded while writing the channel")
+		}
+
+		select {
+		case val := <-out:
+			if val != 42 {
+				t.Errorf("wrong pipeline result: got %d, want 42", val)
+			}
+		case <-time.After(50 * time.Millisecond):
+			t.Fatal("timeout exceeded while reading the channel")
+		}
+
+		close(in)
+
+		if _, ok := <-out; ok {
+			t.Error("channel isn't closed, but expected")
+		}
+	})
+}
+
+func TestPipelineBuilderWithoutStages(t *testing.T) {
+	in, out := NewPipeline[int]().Build()
+
+	go func() {
+		in <- 1
+		close(in)
+	}()
+
+	if val := <-out; val != 1 {
+		t.Errorf("wrong pipeline result: got %d, want 1", val)
+	}
+}
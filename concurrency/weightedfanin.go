@@ -0,0 +1,240 @@
+package concurrency
+
+import (
+	"context"
+	"reflect"
+	"sort"
+)
+
+// WeightedSource is a source channel of WeightedFanIn with its weight.
+type WeightedSource[T any] struct {
+	Src    <-chan T
+	Weight int
+}
+
+// PrioritySource is a source channel of PriorityFanIn with its priority level.
+type PrioritySource[T any] struct {
+	Src      <-chan T
+	Priority int
+}
+
+// WeightedFanIn is a FanIn variant where the sources don't compete equally for
+// the destination channel. Instead, the values are taken in the smooth weighted round-robin
+// order, so while all the sources have values ready, a source with weight W gets exactly
+// W of every (sum of weights) values forwarded to the destination, and the values of the
+// different sources are interleaved rather than sent in bursts.
+// Non-positive weights are treated as 1.
+//
+// A single goroutine selects the values, so if the source that is next in turn has
+// no value ready, the next one in the round-robin order is served instead and waiting
+// for the slow source never stalls the others.
+// The destination channel is closed when all the sources are drained or the context is done.
+func WeightedFanIn[T any](ctx context.Context, sources ...WeightedSource[T]) <-chan T {
+	chans := make([]<-chan T, len(sources))
+	p := &weightedPicker{
+		weights: make([]int, len(sources)),
+		current: make([]int, len(sources)),
+	}
+
+	for i, src := range sources {
+		chans[i] = src.Src
+		p.weights[i] = src.Weight
+		if p.weights[i] <= 0 {
+			p.weights[i] = 1
+		}
+	}
+
+	return selectFanIn[T](ctx, chans, p)
+}
+
+// PriorityFanIn is a FanIn variant with the strict-priority selection: a value from the source
+// with a lower priority is forwarded only if no source with a higher priority has a value ready.
+// The sources of the same priority are served in the order they are passed. Note that a chatty
+// source of a high priority starves all the sources below it, use WeightedFanIn if every
+// source has to make progress.
+// The destination channel is closed when all the sources are drained or the context is done.
+func PriorityFanIn[T any](ctx context.Context, sources ...PrioritySource[T]) <-chan T {
+	chans := make([]<-chan T, len(sources))
+	order := make([]int, len(sources))
+
+	for i, src := range sources {
+		chans[i] = src.Src
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return sources[order[i]].Priority > sources[order[j]].Priority
+	})
+
+	return selectFanIn[T](ctx, chans, &priorityPicker{byPriority: order})
+}
+
+// sourcePicker decides which source of selectFanIn should be served next.
+// Its methods are called from a single goroutine.
+type sourcePicker interface {
+	// order returns the indexes of the live sources in the order of preference for the next value.
+	order(live []bool) []int
+	// served is called when the value from i-th source is forwarded.
+	served(i int)
+	// closed is called when i-th source is found closed.
+	closed(i int)
+}
+
+// selectFanIn forwards values from the sources to the destination channel in a single goroutine.
+// The sources are polled without blocking in the order given by the picker. If none of them is ready,
+// it blocks until any source provides a value.
+func selectFanIn[T any](ctx context.Context, sources []<-chan T, p sourcePicker) <-chan T {
+	dest := make(chan T)
+
+	go func() {
+		defer close(dest)
+
+		live := make([]bool, len(sources))
+		liveNum := len(sources)
+
+		for i := range live {
+			live[i] = true
+		}
+
+		for liveNum > 0 {
+			idx, val, ok := pollSources(sources, p.order(live))
+			if idx < 0 {
+				idx, val, ok = waitSources(ctx, sources, live)
+				if idx < 0 {
+					return
+				}
+			}
+
+			if !ok {
+				live[idx] = false
+				liveNum--
+				p.closed(idx)
+
+				continue
+			}
+
+			p.served(idx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case dest <- val:
+			}
+		}
+	}()
+
+	return dest
+}
+
+// pollSources tries to receive from the sources in the given order without blocking.
+// It returns the index of the source it has received from, or -1 if none of them is ready.
+func pollSources[T any](sources []<-chan T, order []int) (int, T, bool) {
+	for _, i := range order {
+		select {
+		case val, ok := <-sources[i]:
+			return i, val, ok
+		default:
+		}
+	}
+
+	var zero T
+
+	return -1, zero, false
+}
+
+// waitSources blocks until any of the live sources is ready. It returns -1 if the context is done.
+func waitSources[T any](ctx context.Context, sources []<-chan T, live []bool) (int, T, bool) {
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}}
+	indexes := []int{-1}
+
+	for i, src := range sources {
+		if live[i] {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(src)})
+			indexes = append(indexes, i)
+		}
+	}
+
+	var zero T
+
+	chosen, recv, ok := reflect.Select(cases)
+	if chosen == 0 {
+		return -1, zero, false
+	}
+
+	if !ok {
+		return indexes[chosen], zero, false
+	}
+
+	// The comma-ok form keeps nil values of interface types from panicking.
+	val, _ := recv.Interface().(T)
+
+	return indexes[chosen], val, true
+}
+
+// weightedPicker implements the smooth weighted round-robin selection:
+// every round each live source earns its weight, the served one pays the total weight.
+// The round is settled only when a value is served, so the polls finding the closed sources
+// don't skew the credits. Once a source is closed, the credits are reset, and the rest of
+// the sources start a fresh round-robin, not affected by the share of the closed one.
+type weightedPicker struct {
+	weights []int
+	current []int
+	// round holds the live sources of the last order call.
+	round []int
+}
+
+func (p *weightedPicker) order(live []bool) []int {
+	p.round = p.round[:0]
+
+	for i, ok := range live {
+		if ok {
+			p.round = append(p.round, i)
+		}
+	}
+
+	order := append([]int(nil), p.round...)
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return p.current[order[i]]+p.weights[order[i]] > p.current[order[j]]+p.weights[order[j]]
+	})
+
+	return order
+}
+
+func (p *weightedPicker) served(i int) {
+	total := 0
+
+	for _, j := range p.round {
+		p.current[j] += p.weights[j]
+		total += p.weights[j]
+	}
+
+	p.current[i] -= total
+}
+
+func (p *weightedPicker) closed(int) {
+	for i := range p.current {
+		p.current[i] = 0
+	}
+}
+
+// priorityPicker always prefers the sources with higher priority.
+type priorityPicker struct {
+	byPriority []int
+}
+
+func (p *priorityPicker) order(live []bool) []int {
+	order := make([]int, 0, len(p.byPriority))
+
+	for _, i := range p.byPriority {
+		if live[i] {
+			order = append(order, i)
+		}
+	}
+
+	return order
+}
+
+func (p *priorityPicker) served(int) {}
+
+func (p *priorityPicker) closed(int) {}
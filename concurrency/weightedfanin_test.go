@@ -0,0 +1,113 @@
+package concurrency
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Mort4lis/go-design-patterns/testutils"
+)
+
+// filledChan returns the closed channel that has n ready values equal to val.
+func filledChan(val string, n int) <-chan string {
+	ch := make(chan string, n)
+	for i := 0; i < n; i++ {
+		ch <- val
+	}
+
+	close(ch)
+
+	return ch
+}
+
+func TestWeightedFanIn(t *testing.T) {
+	dest := WeightedFanIn(
+		context.Background(),
+		WeightedSource[string]{Src: filledChan("a", 6), Weight: 3},
+		WeightedSource[string]{Src: filledChan("b", 4), Weight: 1},
+	)
+
+	var got []string
+	for val := range dest {
+		got = append(got, val)
+	}
+
+	// While both sources are ready, "a" gets 3 of every 4 values,
+	// then the rest of "b" is drained.
+	want := []string{"a", "a", "b", "a", "a", "a", "b", "a", "b", "b"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong WeightedFanIn result: got %v, want %v", got, want)
+	}
+}
+
+func TestWeightedFanInSourceClosesEarly(t *testing.T) {
+	dest := WeightedFanIn(
+		context.Background(),
+		WeightedSource[string]{Src: filledChan("a", 1), Weight: 5},
+		WeightedSource[string]{Src: filledChan("b", 6), Weight: 1},
+		WeightedSource[string]{Src: filledChan("c", 6), Weight: 2},
+	)
+
+	var got []string
+	for val := range dest {
+		got = append(got, val)
+	}
+
+	// Once "a" is found closed, "c" gets 2 of every 3 values, then the rest of "b" is drained.
+	want := []string{"a", "c", "c", "b", "c", "c", "b", "c", "c", "b", "b", "b", "b"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong WeightedFanIn result: got %v, want %v", got, want)
+	}
+}
+
+func TestWeightedFanInSlowSource(t *testing.T) {
+	testutils.DetectGoroutineLeeks(t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := make(chan string)
+
+		dest := WeightedFanIn(
+			ctx,
+			WeightedSource[string]{Src: slow, Weight: 10},
+			WeightedSource[string]{Src: filledChan("b", 3), Weight: 1},
+		)
+
+		// The heavy source has nothing to send, it mustn't stall the other one.
+		for i := 0; i < 3; i++ {
+			if val := <-dest; val != "b" {
+				t.Errorf("wrong WeightedFanIn result: got %s, want b", val)
+			}
+		}
+
+		slow <- "a"
+		if val := <-dest; val != "a" {
+			t.Errorf("wrong WeightedFanIn result: got %s, want a", val)
+		}
+
+		cancel()
+
+		for range dest {
+		}
+	})
+}
+
+func TestPriorityFanIn(t *testing.T) {
+	dest := PriorityFanIn(
+		context.Background(),
+		PrioritySource[string]{Src: filledChan("low", 2), Priority: 1},
+		PrioritySource[string]{Src: filledChan("high", 3), Priority: 10},
+		PrioritySource[string]{Src: filledChan("mid", 2), Priority: 5},
+	)
+
+	var got []string
+	for val := range dest {
+		got = append(got, val)
+	}
+
+	want := []string{"high", "high", "high", "mid", "mid", "low", "low"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong PriorityFanIn result: got %v, want %v", got, want)
+	}
+}
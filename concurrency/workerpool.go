@@ -1,38 +1,29 @@
 package concurrency
 
-import (
-	"sync"
-)
+import "context"
 
 // WorkerFunc is a function type that workers perform.
 type WorkerFunc func(in any) any
 
+// ContextWorkerFunc is WorkerFunc that takes the context, which is canceled when the work
+// has to be aborted, e.g. when the pool is stopped. The function is expected to return
+// as soon as possible after that.
+type ContextWorkerFunc func(ctx context.Context, in any) any
+
 // WorkerPool pattern distributes the work across multiple workers (goroutines) concurrently.
 // It takes the number of workers and function of WorkerFunc type that the workers will perform.
 // It also returns two channels: the first to send some value to start processing among the workers
 // and the second to handle results. If the sending channel is closed, it'll close all the workers and
 // WorkerPool will be finished.
-func WorkerPool(n int, fn WorkerFunc) (chan<- any, <-chan any) {
-	in := make(chan any)
-	out := make(chan any)
-
-	var wg sync.WaitGroup
-
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			for val := range in {
-				out <- fn(val)
-			}
-		}()
-	}
-
-	go func() {
-		wg.Wait()
-		close(out)
-	}()
-
-	return in, out
+//
+// WorkerPool is a shortcut for the Pool with the fixed number of workers, use NewPool
+// to control the pool at runtime. As the Pool, it has at least one worker, n below 1 is
+// raised to 1. The sending channel is unbuffered, but the pool buffers the values: besides
+// the ones being processed by n workers, one value waits in the queue (see WithQueueSize)
+// and one more is held by the goroutine that reads the channel, so up to n+2 sends succeed
+// before the sender blocks. A panicking function crashes the process unless WithDeadLetters
+// option is given.
+func WorkerPool(n int, fn WorkerFunc, opts ...PoolOption) (chan<- any, <-chan any) {
+	p := NewPool(n, fn, opts...)
+	return p.In(), p.Out()
 }
@@ -3,6 +3,7 @@ package testutils
 import (
 	"runtime"
 	"testing"
+	"time"
 )
 
 func DetectGoroutineLeeks(t *testing.T, fn func()) {
@@ -12,6 +13,30 @@ func DetectGoroutineLeeks(t *testing.T, fn func()) {
 	fn()
 	afterNum := runtime.NumGoroutine()
 
+	reportLeeks(t, beforeNum, afterNum)
+}
+
+// DetectGoroutineLeeksWithGrace is DetectGoroutineLeeks that gives the goroutines, which are
+// already finishing when fn returns (e.g. right after closing a channel), up to the grace
+// period to exit before reporting them as leeked.
+func DetectGoroutineLeeksWithGrace(t *testing.T, grace time.Duration, fn func()) {
+	t.Helper()
+
+	beforeNum := runtime.NumGoroutine()
+	fn()
+	afterNum := runtime.NumGoroutine()
+
+	for deadline := time.Now().Add(grace); beforeNum < afterNum && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+		afterNum = runtime.NumGoroutine()
+	}
+
+	reportLeeks(t, beforeNum, afterNum)
+}
+
+func reportLeeks(t *testing.T, beforeNum, afterNum int) {
+	t.Helper()
+
 	if beforeNum < afterNum {
 		buf := make([]byte, 4096)
 		runtime.Stack(buf, true)